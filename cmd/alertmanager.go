@@ -0,0 +1,74 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// alertmanagerConfigCmd represents the alertmanager subcommand
+var alertmanagerConfigCmd = &cobra.Command{
+	Use:   "alertmanager",
+	Short: "specific alertmanager configuration",
+	Long:  `specific alertmanager configuration`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		handler := handlerConfig(cmd, conf, "alertmanager")
+
+		url, err := cmd.Flags().GetString("url")
+		if err == nil {
+			if len(url) > 0 {
+				handler.Alertmanager.Url = url
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		username, err := cmd.Flags().GetString("username")
+		if err == nil {
+			if len(username) > 0 {
+				handler.Alertmanager.Username = username
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		password, err := cmd.Flags().GetString("password")
+		if err == nil {
+			if len(password) > 0 {
+				handler.Alertmanager.Password = password
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		if err = conf.Write(); err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	alertmanagerConfigCmd.Flags().StringP("url", "u", "", "Specify Alertmanager url")
+	alertmanagerConfigCmd.Flags().StringP("username", "", "", "Specify Alertmanager basic auth username")
+	alertmanagerConfigCmd.Flags().StringP("password", "", "", "Specify Alertmanager basic auth password")
+}
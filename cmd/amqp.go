@@ -0,0 +1,74 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// amqpConfigCmd represents the amqp subcommand
+var amqpConfigCmd = &cobra.Command{
+	Use:   "amqp",
+	Short: "specific amqp configuration",
+	Long:  `specific amqp configuration`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		handler := handlerConfig(cmd, conf, "amqp")
+
+		url, err := cmd.Flags().GetString("url")
+		if err == nil {
+			if len(url) > 0 {
+				handler.AMQP.Url = url
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		exchange, err := cmd.Flags().GetString("exchange")
+		if err == nil {
+			if len(exchange) > 0 {
+				handler.AMQP.Exchange = exchange
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		routingKey, err := cmd.Flags().GetString("routingkey")
+		if err == nil {
+			if len(routingKey) > 0 {
+				handler.AMQP.RoutingKey = routingKey
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		if err = conf.Write(); err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	amqpConfigCmd.Flags().StringP("url", "u", "", "Specify AMQP url")
+	amqpConfigCmd.Flags().StringP("exchange", "e", "", "Specify AMQP exchange")
+	amqpConfigCmd.Flags().StringP("routingkey", "k", "", "Specify the template of AMQP routing keys")
+}
@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/client"
@@ -63,7 +64,7 @@ Tests handler configs present in ~/.kubewatch.yaml by sending test messages`,
 		if err != nil {
 			logrus.Fatal(err)
 		}
-		eventHandler := client.ParseEventHandler(conf)
+		eventHandler := client.TestEventHandler(conf)
 		e := event.Event{
 			Namespace: "testNamespace",
 			Name:      "testResource",
@@ -72,8 +73,11 @@ Tests handler configs present in ~/.kubewatch.yaml by sending test messages`,
 			Host:      "testHost",
 			Reason:    "Tested",
 			Status:    "Normal",
+			Timestamp: time.Now(),
+		}
+		if err := eventHandler.Handle(e); err != nil {
+			logrus.Fatal(err)
 		}
-		eventHandler.Handle(e)
 	},
 }
 
@@ -103,6 +107,30 @@ Display the contents of the contents of ~/.kubewatch.yaml`,
 	},
 }
 
+// handlerConfig returns the configuration to modify for a handler type:
+// the named handler given with --name, created if needed, or the default one
+func handlerConfig(cmd *cobra.Command, conf *config.Config, handlerType string) *config.Handler {
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if name == "" {
+		return &conf.Handler
+	}
+	for i := range conf.Handlers {
+		h := &conf.Handlers[i]
+		if h.Name != name {
+			continue
+		}
+		if h.Type != handlerType {
+			logrus.Fatalf("handler %q is of type %s, not %s", name, h.Type, handlerType)
+		}
+		return &h.Handler
+	}
+	conf.Handlers = append(conf.Handlers, config.NamedHandler{Name: name, Type: handlerType})
+	return &conf.Handlers[len(conf.Handlers)-1].Handler
+}
+
 func init() {
 	RootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(
@@ -112,6 +140,8 @@ func init() {
 		configViewCmd,
 	)
 
+	configAddCmd.PersistentFlags().StringP("name", "", "", "Specify a handler name, to configure several handlers of the same type")
+
 	configAddCmd.AddCommand(
 		slackConfigCmd,
 		hipchatConfigCmd,
@@ -120,5 +150,13 @@ func init() {
 		webhookConfigCmd,
 		msteamsConfigCmd,
 		smtpConfigCmd,
+		pagerdutyConfigCmd,
+		opsgenieConfigCmd,
+		alertmanagerConfigCmd,
+		kafkaConfigCmd,
+		natsConfigCmd,
+		amqpConfigCmd,
+		mqttConfigCmd,
+		redisConfigCmd,
 	)
 }
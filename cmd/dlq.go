@@ -0,0 +1,187 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/client"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// dlqCmd represents the dlq command
+var dlqCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "manage notifications that could not be delivered",
+	Long: `
+manage the dead-letter store of notifications that could not be delivered,
+kept in the delivery.queueDir directory of ~/.kubewatch.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// dlqListCmd represents the dlq list subcommand
+var dlqListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list notifications that could not be delivered",
+	Long: `
+list notifications that could not be delivered`,
+	Run: func(cmd *cobra.Command, args []string) {
+		_, _, records := deadRecords(cmd, args)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tHANDLER\tFAILED\tKIND\tNAMESPACE\tNAME\tREASON\tERROR")
+		for _, r := range records {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.Handler, r.Failed.Format("2006-01-02T15:04:05Z07:00"),
+				r.Event.Kind, r.Event.Namespace, r.Event.Name, r.Event.Reason, r.Error)
+		}
+		w.Flush()
+	},
+}
+
+// dlqReplayCmd represents the dlq replay subcommand
+var dlqReplayCmd = &cobra.Command{
+	Use:   "replay [ID...]",
+	Short: "resend notifications that could not be delivered",
+	Long: `
+resend notifications that could not be delivered, all of them or the given ones,
+removing them from the dead-letter store once delivered`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, spool, records := deadRecords(cmd, args)
+		eventHandlers := client.EventHandlers(conf)
+
+		failed := 0
+		for _, r := range records {
+			h, ok := eventHandlers[r.Handler]
+			if !ok {
+				logrus.Errorf("%s: handler %s is no longer configured", r.ID, r.Handler)
+				failed++
+				continue
+			}
+			if err := h.Handle(r.Event); err != nil {
+				logrus.Errorf("%s: %v", r.ID, err)
+				failed++
+				continue
+			}
+			if err := spool.Remove(r); err != nil {
+				logrus.Fatal(err)
+			}
+			fmt.Printf("%s: delivered to %s\n", r.ID, r.Handler)
+		}
+
+		// the handlers sending in the background, e.g. kafka, flush once closed
+		names := make([]string, 0, len(eventHandlers))
+		for name := range eventHandlers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := handlers.Close(eventHandlers[name]); err != nil {
+				logrus.Errorf("closing %s: %v", name, err)
+			}
+		}
+		if failed > 0 {
+			logrus.Fatalf("%d of %d notifications could not be delivered", failed, len(records))
+		}
+	},
+}
+
+// dlqPurgeCmd represents the dlq purge subcommand
+var dlqPurgeCmd = &cobra.Command{
+	Use:   "purge [ID...]",
+	Short: "delete notifications that could not be delivered",
+	Long: `
+delete notifications that could not be delivered: the given ones, the ones
+of a handler with --handler, or all of them with --all`,
+	Run: func(cmd *cobra.Command, args []string) {
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		handler, err := cmd.Flags().GetString("handler")
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		if len(args) == 0 && handler == "" && !all {
+			logrus.Fatal("give the IDs of the notifications to delete, --handler or --all")
+		}
+
+		_, spool, records := deadRecords(cmd, args)
+		for _, r := range records {
+			if err := spool.Remove(r); err != nil {
+				logrus.Fatal(err)
+			}
+		}
+		fmt.Printf("%d notifications deleted\n", len(records))
+	},
+}
+
+// deadRecords returns the dead-lettered notifications selected by the
+// --handler flag and the IDs given as arguments
+func deadRecords(cmd *cobra.Command, ids []string) (*config.Config, *delivery.Spool, []*delivery.Record) {
+	conf, err := config.New()
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if conf.Delivery.QueueDir == "" {
+		logrus.Fatal("delivery.queueDir is not set in ~/.kubewatch.yaml, notifications are not stored on disk")
+	}
+	spool, err := delivery.OpenSpool(conf.Delivery.QueueDir)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	handler, err := cmd.Flags().GetString("handler")
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	records, err := spool.Dead(handler)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if len(ids) == 0 {
+		return conf, spool, records
+	}
+
+	var selected []*delivery.Record
+	for _, r := range records {
+		if containsString(ids, r.ID) {
+			selected = append(selected, r)
+		}
+	}
+	return conf, spool, selected
+}
+
+func init() {
+	RootCmd.AddCommand(dlqCmd)
+	dlqCmd.AddCommand(
+		dlqListCmd,
+		dlqReplayCmd,
+		dlqPurgeCmd,
+	)
+
+	dlqCmd.PersistentFlags().StringP("handler", "", "", "Only consider the notifications of this handler")
+	dlqPurgeCmd.Flags().BoolP("all", "", false, "Delete all notifications")
+}
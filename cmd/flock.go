@@ -32,11 +32,12 @@ var flockConfigCmd = &cobra.Command{
 		if err != nil {
 			logrus.Fatal(err)
 		}
+		handler := handlerConfig(cmd, conf, "flock")
 
 		url, err := cmd.Flags().GetString("url")
 		if err == nil {
 			if len(url) > 0 {
-				conf.Handler.Flock.Url = url
+				handler.Flock.Url = url
 			}
 		} else {
 			logrus.Fatal(err)
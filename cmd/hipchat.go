@@ -32,11 +32,12 @@ var hipchatConfigCmd = &cobra.Command{
 		if err != nil {
 			logrus.Fatal(err)
 		}
+		handler := handlerConfig(cmd, conf, "hipchat")
 
 		token, err := cmd.Flags().GetString("token")
 		if err == nil {
 			if len(token) > 0 {
-				conf.Handler.Hipchat.Token = token
+				handler.Hipchat.Token = token
 			}
 		} else {
 			logrus.Fatal(err)
@@ -44,7 +45,7 @@ var hipchatConfigCmd = &cobra.Command{
 		room, err := cmd.Flags().GetString("room")
 		if err == nil {
 			if len(room) > 0 {
-				conf.Handler.Hipchat.Room = room
+				handler.Hipchat.Room = room
 			}
 		} else {
 			logrus.Fatal(err)
@@ -32,11 +32,12 @@ var mattermostConfigCmd = &cobra.Command{
 		if err != nil {
 			logrus.Fatal(err)
 		}
+		handler := handlerConfig(cmd, conf, "mattermost")
 
 		channel, err := cmd.Flags().GetString("channel")
 		if err == nil {
 			if len(channel) > 0 {
-				conf.Handler.Mattermost.Channel = channel
+				handler.Mattermost.Channel = channel
 			}
 		} else {
 			logrus.Fatal(err)
@@ -45,7 +46,7 @@ var mattermostConfigCmd = &cobra.Command{
 		url, err := cmd.Flags().GetString("url")
 		if err == nil {
 			if len(url) > 0 {
-				conf.Handler.Mattermost.Url = url
+				handler.Mattermost.Url = url
 			}
 		} else {
 			logrus.Fatal(err)
@@ -54,7 +55,7 @@ var mattermostConfigCmd = &cobra.Command{
 		username, err := cmd.Flags().GetString("username")
 		if err == nil {
 			if len(url) > 0 {
-				conf.Handler.Mattermost.Username = username
+				handler.Mattermost.Username = username
 			}
 		} else {
 			logrus.Fatal(err)
@@ -0,0 +1,92 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// mqttConfigCmd represents the mqtt subcommand
+var mqttConfigCmd = &cobra.Command{
+	Use:   "mqtt",
+	Short: "specific mqtt configuration",
+	Long:  `specific mqtt configuration`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		handler := handlerConfig(cmd, conf, "mqtt")
+
+		url, err := cmd.Flags().GetString("url")
+		if err == nil {
+			if len(url) > 0 {
+				handler.MQTT.Url = url
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		topic, err := cmd.Flags().GetString("topic")
+		if err == nil {
+			if len(topic) > 0 {
+				handler.MQTT.Topic = topic
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		clientID, err := cmd.Flags().GetString("clientid")
+		if err == nil {
+			if len(clientID) > 0 {
+				handler.MQTT.ClientID = clientID
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		if cmd.Flags().Changed("qos") {
+			qos, err := cmd.Flags().GetInt("qos")
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			handler.MQTT.QoS = qos
+		}
+
+		if cmd.Flags().Changed("retain") {
+			retain, err := cmd.Flags().GetBool("retain")
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			handler.MQTT.Retain = retain
+		}
+
+		if err = conf.Write(); err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	mqttConfigCmd.Flags().StringP("url", "u", "", "Specify MQTT url")
+	mqttConfigCmd.Flags().StringP("topic", "t", "", "Specify the template of MQTT topics")
+	mqttConfigCmd.Flags().String("clientid", "", "Specify MQTT client ID")
+	mqttConfigCmd.Flags().Int("qos", 0, "Specify MQTT QoS: 0, 1 or 2")
+	mqttConfigCmd.Flags().Bool("retain", false, "Retain the last event of each topic")
+}
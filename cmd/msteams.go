@@ -32,11 +32,12 @@ var msteamsConfigCmd = &cobra.Command{
 		if err != nil {
 			logrus.Fatal(err)
 		}
+		handler := handlerConfig(cmd, conf, "msteams")
 
 		webhookURL, err := cmd.Flags().GetString("webhookurl")
 		if err == nil {
 			if len(webhookURL) > 0 {
-				conf.Handler.MSTeams.WebhookURL = webhookURL
+				handler.MSTeams.WebhookURL = webhookURL
 			}
 		} else {
 			logrus.Fatal(err)
@@ -0,0 +1,145 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// namespaceConfigCmd represents the namespace subcommand
+var namespaceConfigCmd = &cobra.Command{
+	Use:   "namespace",
+	Short: "manage namespaces to be watched",
+	Long: `
+manage namespaces to be watched and namespaces to be ignored`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// namespaceConfigAddCmd represents the namespace add subcommand
+var namespaceConfigAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "adds namespaces to be watched or ignored",
+	Long: `
+adds namespaces to be watched or ignored`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		// add namespaces to config
+		configureNamespaces("add", cmd, conf)
+	},
+}
+
+// namespaceConfigRemoveCmd represents the namespace remove subcommand
+var namespaceConfigRemoveCmd = &cobra.Command{
+	Use:   "remove",
+	Short: "remove namespaces being watched or ignored",
+	Long: `
+remove namespaces being watched or ignored`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		// remove namespaces from config
+		configureNamespaces("remove", cmd, conf)
+	},
+}
+
+// configures namespace lists in config based on operation add/remove
+func configureNamespaces(operation string, cmd *cobra.Command, conf *config.Config) {
+
+	// flags struct
+	flags := []struct {
+		flagStr string
+		list    *[]string
+	}{
+		{
+			"watch",
+			&conf.Namespaces,
+		},
+		{
+			"exclude",
+			&conf.ExcludeNamespaces,
+		},
+	}
+
+	for _, flag := range flags {
+		values, err := cmd.Flags().GetStringSlice(flag.flagStr)
+		if err != nil {
+			logrus.Fatal(flag.flagStr, err)
+		}
+		for _, v := range values {
+			switch operation {
+			case "add":
+				if flag.flagStr == "exclude" {
+					if _, err := config.NamespacePattern(v); err != nil {
+						logrus.Fatal(err)
+					}
+				}
+				if !containsString(*flag.list, v) {
+					*flag.list = append(*flag.list, v)
+				}
+				logrus.Infof("namespace %s added to %s list", v, flag.flagStr)
+			case "remove":
+				*flag.list = removeString(*flag.list, v)
+				logrus.Infof("namespace %s removed from %s list", v, flag.flagStr)
+			}
+		}
+	}
+
+	if err := conf.Write(); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(list []string, s string) []string {
+	var out []string
+	for _, item := range list {
+		if item != s {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func init() {
+	RootCmd.AddCommand(namespaceConfigCmd)
+	namespaceConfigCmd.AddCommand(
+		namespaceConfigAddCmd,
+		namespaceConfigRemoveCmd,
+	)
+	// Add namespace list flags as PersistentFlags to namespaceConfigCmd
+	namespaceConfigCmd.PersistentFlags().StringSlice("watch", nil, "namespaces to watch, all namespaces are watched when none is set")
+	namespaceConfigCmd.PersistentFlags().StringSlice("exclude", nil, "namespaces to ignore, as globs (e.g. kube-*) or regular expressions between slashes")
+}
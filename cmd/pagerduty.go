@@ -0,0 +1,64 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// pagerdutyConfigCmd represents the pagerduty subcommand
+var pagerdutyConfigCmd = &cobra.Command{
+	Use:   "pagerduty",
+	Short: "specific pagerduty configuration",
+	Long:  `specific pagerduty configuration`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		handler := handlerConfig(cmd, conf, "pagerduty")
+
+		routingKey, err := cmd.Flags().GetString("routingkey")
+		if err == nil {
+			if len(routingKey) > 0 {
+				handler.PagerDuty.RoutingKey = routingKey
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		url, err := cmd.Flags().GetString("url")
+		if err == nil {
+			if len(url) > 0 {
+				handler.PagerDuty.Url = url
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		if err = conf.Write(); err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	pagerdutyConfigCmd.Flags().StringP("routingkey", "k", "", "Specify PagerDuty routing key")
+	pagerdutyConfigCmd.Flags().StringP("url", "u", "", "Specify PagerDuty Events API url")
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// redisConfigCmd represents the redis subcommand
+var redisConfigCmd = &cobra.Command{
+	Use:   "redis",
+	Short: "specific redis configuration",
+	Long:  `specific redis configuration`,
+	Run: func(cmd *cobra.Command, args []string) {
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		handler := handlerConfig(cmd, conf, "redis")
+
+		url, err := cmd.Flags().GetString("url")
+		if err == nil {
+			if len(url) > 0 {
+				handler.Redis.Url = url
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		stream, err := cmd.Flags().GetString("stream")
+		if err == nil {
+			if len(stream) > 0 {
+				handler.Redis.Stream = stream
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		channel, err := cmd.Flags().GetString("channel")
+		if err == nil {
+			if len(channel) > 0 {
+				handler.Redis.Channel = channel
+			}
+		} else {
+			logrus.Fatal(err)
+		}
+
+		if cmd.Flags().Changed("maxlen") {
+			maxLen, err := cmd.Flags().GetInt("maxlen")
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			handler.Redis.MaxLen = maxLen
+		}
+
+		if err = conf.Write(); err != nil {
+			logrus.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	redisConfigCmd.Flags().StringP("url", "u", "", "Specify Redis url")
+	redisConfigCmd.Flags().StringP("stream", "s", "", "Specify Redis stream")
+	redisConfigCmd.Flags().Int("maxlen", 0, "Specify the approximate maximum length of the Redis stream")
+	redisConfigCmd.Flags().StringP("channel", "c", "", "Specify Redis Pub/Sub channel, instead of a stream")
+}
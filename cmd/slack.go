@@ -32,11 +32,12 @@ var slackConfigCmd = &cobra.Command{
 		if err != nil {
 			logrus.Fatal(err)
 		}
+		handler := handlerConfig(cmd, conf, "slack")
 
 		token, err := cmd.Flags().GetString("token")
 		if err == nil {
 			if len(token) > 0 {
-				conf.Handler.Slack.Token = token
+				handler.Slack.Token = token
 			}
 		} else {
 			logrus.Fatal(err)
@@ -44,7 +45,7 @@ var slackConfigCmd = &cobra.Command{
 		channel, err := cmd.Flags().GetString("channel")
 		if err == nil {
 			if len(channel) > 0 {
-				conf.Handler.Slack.Channel = channel
+				handler.Slack.Channel = channel
 			}
 		} else {
 			logrus.Fatal(err)
@@ -52,7 +53,7 @@ var slackConfigCmd = &cobra.Command{
 		title, err := cmd.Flags().GetString("title")
 		if err == nil {
 			if len(title) > 0 {
-				conf.Handler.Slack.Title = title
+				handler.Slack.Title = title
 			}
 		}
 
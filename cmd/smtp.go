@@ -20,7 +20,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/smtp"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -30,9 +32,41 @@ var smtpConfigCmd = &cobra.Command{
 	Short: "specific smtp configuration",
 	Long:  `specific smtp configuration`,
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Fprintf(os.Stderr, "CLI setters not implemented yet, please edit ~/.kubewatch.yaml directly. Example:\n\n%s", smtp.ConfigExample)
+		if cmd.Flags().NFlag() == 0 {
+			fmt.Fprintf(os.Stderr, "Set the fields with flags, or edit ~/.kubewatch.yaml directly. Example:\n\n%s", smtp.ConfigExample)
+			return
+		}
+
+		conf, err := config.New()
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		handler := handlerConfig(cmd, conf, "smtp")
+
+		for flag, field := range map[string]*string{
+			"to":        &handler.SMTP.To,
+			"from":      &handler.SMTP.From,
+			"smarthost": &handler.SMTP.Smarthost,
+			"subject":   &handler.SMTP.Subject,
+		} {
+			value, err := cmd.Flags().GetString(flag)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			if len(value) > 0 {
+				*field = value
+			}
+		}
+
+		if err = conf.Write(); err != nil {
+			logrus.Fatal(err)
+		}
 	},
 }
 
 func init() {
+	smtpConfigCmd.Flags().StringP("to", "t", "", "Specify the destination e-mail address")
+	smtpConfigCmd.Flags().StringP("from", "f", "", "Specify the sender e-mail address")
+	smtpConfigCmd.Flags().StringP("smarthost", "s", "", "Specify the SMTP server, host:port")
+	smtpConfigCmd.Flags().String("subject", "", "Specify the subject of the e-mails")
 }
@@ -0,0 +1,148 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// templateCmd represents the template command
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "work with message templates",
+	Long: `
+work with the message templates configured in ~/.kubewatch.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// templateRenderCmd represents the template render subcommand
+var templateRenderCmd = &cobra.Command{
+	Use:   "render [TEMPLATE]",
+	Short: "preview a message template against a sample event",
+	Long: `
+render the given template, or the template configured in ~/.kubewatch.yaml
+(of the handler given with --handler, if any), against a sample event, e.g.:
+
+  kubewatch template render '{{ .Reason | upper }} {{ .Kind }} {{ .Namespace }}/{{ .Name }}'
+  kubewatch template render --handler sre-slack --kind node --reason Deleted`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		e, err := sampleEvent(cmd)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+
+		var template config.Template
+		if len(args) > 0 {
+			template.Message = args[0]
+		} else if template, err = configuredTemplate(cmd); err != nil {
+			logrus.Fatal(err)
+		}
+
+		renderer, err := message.NewRenderer(template.Message, template.Kinds)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		msg, err := renderer.Render(e)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		fmt.Println(msg)
+	},
+}
+
+// configuredTemplate returns the template of the handler selected with --handler, or the global template
+func configuredTemplate(cmd *cobra.Command) (config.Template, error) {
+	conf, err := config.New()
+	if err != nil {
+		return config.Template{}, err
+	}
+	name, err := cmd.Flags().GetString("handler")
+	if err != nil || name == "" {
+		return conf.Template, err
+	}
+	for _, h := range conf.Handlers {
+		if h.Name == name {
+			return conf.Template.Merge(h.Template), nil
+		}
+	}
+	return config.Template{}, fmt.Errorf("no handler named %q under handlers", name)
+}
+
+// sampleEvent returns an event for the kind, reason, namespace and name given as flags
+func sampleEvent(cmd *cobra.Command) (event.Event, error) {
+	e := event.Event{
+		Component:       "ClusterIP",
+		Host:            "node-1",
+		UID:             "1b0c5c8e-7d4a-4bf6-a0e5-2bb8f4f3c2a1",
+		APIVersion:      "v1",
+		ResourceVersion: "4212",
+		Labels:          map[string]string{"app": "nginx", "team": "payments"},
+		Annotations:     map[string]string{"owner": "payments@example.com"},
+		OwnerReferences: []meta_v1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "nginx-6799fc88d8"}},
+		Timestamp:       time.Now(),
+	}
+	for _, f := range []struct {
+		flag  string
+		value *string
+	}{
+		{"kind", &e.Kind},
+		{"reason", &e.Reason},
+		{"namespace", &e.Namespace},
+		{"name", &e.Name},
+	} {
+		v, err := cmd.Flags().GetString(f.flag)
+		if err != nil {
+			return e, err
+		}
+		*f.value = v
+	}
+
+	switch e.Reason {
+	case "Created":
+		e.Status = "Normal"
+	case "Updated":
+		e.Status = "Warning"
+		e.Diff = []event.Change{{Path: "spec.containers[0].image", Old: "nginx:1.18", New: "nginx:1.19"}}
+	default:
+		e.Status = "Danger"
+	}
+	return e, nil
+}
+
+func init() {
+	RootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(
+		templateRenderCmd,
+	)
+
+	templateRenderCmd.Flags().StringP("handler", "", "", "Render the template of this named handler")
+	templateRenderCmd.Flags().StringP("kind", "", "pod", "Kind of the sample event")
+	templateRenderCmd.Flags().StringP("reason", "", "Updated", "Reason of the sample event: Created, Updated or Deleted")
+	templateRenderCmd.Flags().StringP("namespace", "", "default", "Namespace of the sample event")
+	templateRenderCmd.Flags().StringP("name", "", "nginx-6799fc88d8-6xzwn", "Name of the sample event")
+}
@@ -32,11 +32,12 @@ var webhookConfigCmd = &cobra.Command{
 		if err != nil {
 			logrus.Fatal(err)
 		}
+		handler := handlerConfig(cmd, conf, "webhook")
 
 		url, err := cmd.Flags().GetString("url")
 		if err == nil {
 			if len(url) > 0 {
-				conf.Handler.Webhook.Url = url
+				handler.Webhook.Url = url
 			}
 		} else {
 			logrus.Fatal(err)
@@ -17,9 +17,13 @@ limitations under the License.
 package config
 
 import (
-//"io/ioutil"
-//"os"
-//"testing"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 var configStr = `
@@ -75,3 +79,246 @@ var configStr = `
 //		t.Fatalf("TestLoad(): %+v", err)
 //	}
 //}
+
+func TestValidate(t *testing.T) {
+	var Tests = []struct {
+		opts  ResourceOptions
+		isErr bool
+	}{
+		{ResourceOptions{}, false},
+		{ResourceOptions{LabelSelector: "team=payments,env in (prod, staging)"}, false},
+		{ResourceOptions{FieldSelector: "spec.nodeName=node-1"}, false},
+		{ResourceOptions{LabelSelector: "team in payments"}, true},
+		{ResourceOptions{FieldSelector: "spec.nodeName"}, true},
+	}
+
+	for _, tt := range Tests {
+		c := &Config{ResourceOptions: map[string]ResourceOptions{"pod": tt.opts}}
+		if err := c.validate(); (err != nil) != tt.isErr {
+			t.Fatalf("validate(%+v): unexpected error %v", tt.opts, err)
+		}
+	}
+}
+
+func TestNamespacePattern(t *testing.T) {
+	var Tests = []struct {
+		pattern   string
+		namespace string
+		match     bool
+	}{
+		{"kube-*", "kube-system", true},
+		{"kube-*", "my-kube-system", false},
+		{"*-sandbox", "alice-sandbox", true},
+		{"*-sandbox", "alice-sandbox-2", false},
+		{"team-?", "team-a", true},
+		{"team.a", "teamxa", false},
+		{"/^team-[0-9]+$/", "team-42", true},
+		{"/^team-[0-9]+$/", "team-a", false},
+	}
+
+	for _, tt := range Tests {
+		re, err := NamespacePattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("NamespacePattern(%q): %v", tt.pattern, err)
+		}
+		if got := re.MatchString(tt.namespace); got != tt.match {
+			t.Fatalf("NamespacePattern(%q) matching %q: expected %v, got %v", tt.pattern, tt.namespace, tt.match, got)
+		}
+	}
+
+	if _, err := NamespacePattern("/[/"); err == nil {
+		t.Fatalf("expected an error for an invalid regular expression")
+	}
+}
+
+func TestValidateFilters(t *testing.T) {
+	var Tests = []struct {
+		filter Filter
+		isErr  bool
+	}{
+		{Filter{Expression: `event.reason == "Deleted"`}, false},
+		{Filter{Expression: `object.metadata.labels.env == "prod"`, Action: "exclude"}, false},
+		{Filter{Expression: `event.reason == `}, true},
+		{Filter{Expression: `event.reason == "Deleted"`, Action: "drop"}, true},
+	}
+
+	for _, tt := range Tests {
+		c := &Config{Filters: []Filter{tt.filter}}
+		if err := c.validate(); (err != nil) != tt.isErr {
+			t.Fatalf("validate(%+v): unexpected error %v", tt.filter, err)
+		}
+	}
+}
+
+func TestNamedHandlers(t *testing.T) {
+	in := `
+handler:
+  slack:
+    token: legacy
+    channel: general
+handlers:
+- name: sre-slack
+  type: slack
+  token: sre
+  channel: sre
+- name: payments-slack
+  type: slack
+  token: payments
+  channel: payments
+`
+	var c Config
+	if err := yaml.Unmarshal([]byte(in), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.Handler.Slack.Token != "legacy" {
+		t.Errorf("expected the legacy slack token to be kept, got %q", c.Handler.Slack.Token)
+	}
+	if len(c.Handlers) != 2 {
+		t.Fatalf("expected 2 named handlers, got %d", len(c.Handlers))
+	}
+	if h := c.Handlers[1]; h.Name != "payments-slack" || h.Handler.Slack.Channel != "payments" {
+		t.Errorf("unexpected named handler %+v", h)
+	}
+
+	out, err := yaml.Marshal(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Config
+	if err := yaml.Unmarshal(out, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.Handlers, back.Handlers) {
+		t.Errorf("named handlers did not survive a round trip:\n%s", out)
+	}
+
+	for _, typ := range []string{"msteams", "ms-teams"} {
+		in := "handlers:\n- name: teams\n  type: " + typ + "\n  webhookurl: https://example.com\n"
+		if err := yaml.Unmarshal([]byte(in), &c); err != nil {
+			t.Errorf("%s: %v", typ, err)
+		} else if c.Handlers[0].Handler.MSTeams.WebhookURL != "https://example.com" {
+			t.Errorf("%s: unexpected named handler %+v", typ, c.Handlers[0])
+		}
+	}
+
+	if err := yaml.Unmarshal([]byte("handlers:\n- name: x\n  type: carrier-pigeon\n"), &c); err == nil {
+		t.Errorf("expected an error for an unknown handler type")
+	}
+}
+
+func TestValidateHandlers(t *testing.T) {
+	var Tests = []struct {
+		handlers []NamedHandler
+		isErr    bool
+	}{
+		{[]NamedHandler{{Name: "a", Type: "slack"}, {Name: "b", Type: "slack"}}, false},
+		{[]NamedHandler{{Type: "slack"}}, true},
+		{[]NamedHandler{{Name: "a", Type: "slack"}, {Name: "a", Type: "webhook"}}, true},
+	}
+
+	for _, tt := range Tests {
+		c := &Config{Handlers: tt.handlers}
+		if err := c.validate(); (err != nil) != tt.isErr {
+			t.Fatalf("validate(%+v): unexpected error %v", tt.handlers, err)
+		}
+	}
+}
+
+func TestLeaderElectionDurations(t *testing.T) {
+	var Tests = []struct {
+		le            LeaderElection
+		leaseDuration time.Duration
+		isErr         bool
+	}{
+		{LeaderElection{}, 15 * time.Second, false},
+		{LeaderElection{LeaseDuration: "30s", RenewDeadline: "20s", RetryPeriod: "5s"}, 30 * time.Second, false},
+		{LeaderElection{LeaseDuration: "5s"}, 0, true},
+		{LeaderElection{RetryPeriod: "often"}, 0, true},
+	}
+
+	for _, tt := range Tests {
+		leaseDuration, _, _, err := tt.le.Durations()
+		if (err != nil) != tt.isErr {
+			t.Fatalf("Durations(%+v): unexpected error %v", tt.le, err)
+		}
+		if leaseDuration != tt.leaseDuration {
+			t.Errorf("Durations(%+v): expected a lease duration of %s, got %s", tt.le, tt.leaseDuration, leaseDuration)
+		}
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	in := `
+template:
+  message: "{{ .Reason }} {{ .Kind }} {{ .Name }}"
+  kinds:
+    pod: "pod {{ .Name }}"
+handlers:
+- name: sre-slack
+  type: slack
+  channel: sre
+  template:
+    kinds:
+      node: "node {{ .Name }}"
+`
+	var c Config
+	if err := yaml.Unmarshal([]byte(in), &c); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	merged := c.Template.Merge(c.Handlers[0].Template)
+	expected := Template{
+		Message: "{{ .Reason }} {{ .Kind }} {{ .Name }}",
+		Kinds:   map[string]string{"pod": "pod {{ .Name }}", "node": "node {{ .Name }}"},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected %+v, got %+v", expected, merged)
+	}
+
+	out, err := yaml.Marshal(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Config
+	if err := yaml.Unmarshal(out, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back.Handlers, c.Handlers) {
+		t.Errorf("handler templates did not survive a round trip:\n%s", out)
+	}
+
+	c.Handlers[0].Template.Kinds["node"] = "{{ .Name"
+	if err := c.validate(); err == nil {
+		t.Errorf("expected an error for an invalid template")
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	f, err := ioutil.TempFile("", "ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("not a certificate")
+	f.Close()
+
+	if c, err := (TLS{}).Config(); c != nil || err != nil {
+		t.Errorf("expected no TLS configuration when disabled, got %v, %v", c, err)
+	}
+	c, err := TLS{Enabled: true, InsecureSkipVerify: true}.Config()
+	if err != nil || c == nil || !c.InsecureSkipVerify {
+		t.Errorf("unexpected TLS configuration %v, %v", c, err)
+	}
+	for _, tt := range []TLS{
+		{Enabled: true, CAFile: f.Name()},
+		{Enabled: true, CAFile: "/nonexistent/ca.pem"},
+		{Enabled: true, CertFile: f.Name(), KeyFile: f.Name()},
+	} {
+		if _, err := tt.Config(); err == nil {
+			t.Errorf("%+v: expected an error", tt)
+		}
+	}
+}
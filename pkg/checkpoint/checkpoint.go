@@ -0,0 +1,180 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package checkpoint records the objects kubewatch has notified, with a
+fingerprint of their state, so that after a restart the objects listed from
+the cluster can be compared with it to tell which ones were created, updated
+or deleted while kubewatch was down.
+*/
+package checkpoint
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Objects maps resource types to the fingerprints of their objects, by key.
+type Objects map[string]map[string]string
+
+// Store persists checkpoints.
+type Store interface {
+	// Load returns the last saved checkpoint, nil if there is none.
+	Load() (Objects, error)
+	// Save replaces the saved checkpoint.
+	Save(Objects) error
+}
+
+// Checkpoint holds the fingerprints of the objects notified, by resource type and key.
+type Checkpoint struct {
+	store Store
+
+	mu      sync.Mutex
+	objects Objects
+	dirty   bool
+}
+
+// New loads the checkpoint saved in store. Without store, the checkpoint is kept in memory only.
+func New(store Store) (*Checkpoint, error) {
+	c := &Checkpoint{store: store, objects: Objects{}}
+	if store == nil {
+		return c, nil
+	}
+	objects, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if objects != nil {
+		c.objects = objects
+	}
+	return c, nil
+}
+
+// Known reports whether the checkpoint tracks a resource type, see Track.
+func (c *Checkpoint) Known(resourceType string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.objects[resourceType]
+	return ok
+}
+
+// Track records that the objects of a resource type are tracked, even when there are none.
+func (c *Checkpoint) Track(resourceType string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resource(resourceType)
+}
+
+// Get returns the fingerprint of an object.
+func (c *Checkpoint) Get(resourceType, key string) (fingerprint string, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fingerprint, ok = c.objects[resourceType][key]
+	return fingerprint, ok
+}
+
+// Keys returns the keys of the objects of a resource type, sorted.
+func (c *Checkpoint) Keys(resourceType string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	keys := make([]string, 0, len(c.objects[resourceType]))
+	for k := range c.objects[resourceType] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Set records the fingerprint of an object.
+func (c *Checkpoint) Set(resourceType, key, fingerprint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	objects := c.resource(resourceType)
+	if objects[key] != fingerprint {
+		objects[key] = fingerprint
+		c.dirty = true
+	}
+}
+
+// Delete forgets an object.
+func (c *Checkpoint) Delete(resourceType, key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.objects[resourceType][key]; ok {
+		delete(c.objects[resourceType], key)
+		c.dirty = true
+	}
+}
+
+func (c *Checkpoint) resource(resourceType string) map[string]string {
+	objects, ok := c.objects[resourceType]
+	if !ok {
+		objects = map[string]string{}
+		c.objects[resourceType] = objects
+		c.dirty = true
+	}
+	return objects
+}
+
+// Save saves the checkpoint in its store, if it changed since the last save.
+func (c *Checkpoint) Save() error {
+	if c.store == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	objects := make(Objects, len(c.objects))
+	for resourceType, fingerprints := range c.objects {
+		objects[resourceType] = make(map[string]string, len(fingerprints))
+		for k, v := range fingerprints {
+			objects[resourceType][k] = v
+		}
+	}
+	c.dirty = false
+	c.mu.Unlock()
+
+	if err := c.store.Save(objects); err != nil {
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Run saves the checkpoint every interval until ctx is done.
+func (c *Checkpoint) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Save(); err != nil {
+				logrus.Errorf("Error saving checkpoint: %v", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
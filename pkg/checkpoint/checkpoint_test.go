@@ -0,0 +1,122 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// memoryStore counts the saves of a checkpoint
+type memoryStore struct {
+	objects Objects
+	saves   int
+}
+
+func (s *memoryStore) Load() (Objects, error) {
+	return s.objects, nil
+}
+
+func (s *memoryStore) Save(objects Objects) error {
+	s.objects = objects
+	s.saves++
+	return nil
+}
+
+func TestCheckpoint(t *testing.T) {
+	store := &memoryStore{objects: Objects{"pod": {"default/foo": "1"}}}
+	c, err := New(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Known("pod") || c.Known("deployment") {
+		t.Fatalf("expected only pods to be known")
+	}
+	if fp, ok := c.Get("pod", "default/foo"); !ok || fp != "1" {
+		t.Fatalf("expected the loaded fingerprint, got %q, %v", fp, ok)
+	}
+
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if store.saves != 0 {
+		t.Fatalf("expected an unchanged checkpoint not to be saved")
+	}
+
+	c.Set("pod", "default/bar", "2")
+	c.Delete("pod", "default/foo")
+	c.Track("deployment")
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	expected := Objects{"pod": {"default/bar": "2"}, "deployment": {}}
+	if store.saves != 1 || !reflect.DeepEqual(store.objects, expected) {
+		t.Fatalf("expected %v to be saved once, got %v saved %d times", expected, store.objects, store.saves)
+	}
+
+	c.Set("pod", "default/bar", "2")
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if store.saves != 1 {
+		t.Fatalf("expected setting the same fingerprint not to trigger a save")
+	}
+}
+
+func TestStores(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubewatch-checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var Tests = []struct {
+		name  string
+		store Store
+	}{
+		{"file", &FileStore{Path: filepath.Join(dir, "state", "checkpoint.json")}},
+		{"configmap", &ConfigMapStore{Client: fake.NewSimpleClientset(), Namespace: "default", Name: "kubewatch-checkpoint"}},
+	}
+
+	for _, tt := range Tests {
+		objects, err := tt.store.Load()
+		if err != nil || objects != nil {
+			t.Fatalf("%s: expected no checkpoint, got %v, %v", tt.name, objects, err)
+		}
+
+		for _, saved := range []Objects{
+			{"pod": {"default/foo": "1"}},
+			{"pod": {"default/foo": "2", "default/bar": "3"}, "node": {}},
+		} {
+			if err := tt.store.Save(saved); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			loaded, err := tt.store.Load()
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if !reflect.DeepEqual(loaded, saved) {
+				t.Errorf("%s: expected %v, got %v", tt.name, saved, loaded)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// configMapKey is the key of the checkpoint in the binary data of its ConfigMap
+const configMapKey = "checkpoint.json.gz"
+
+// FileStore stores the checkpoint as JSON in a file.
+type FileStore struct {
+	Path string
+}
+
+// Load implements Store.
+func (s *FileStore) Load() (Objects, error) {
+	b, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var objects Objects
+	if err := json.Unmarshal(b, &objects); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Save implements Store. The file is replaced atomically.
+func (s *FileStore) Save(objects Objects) error {
+	b, err := json.Marshal(objects)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.Path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.Path)
+}
+
+// ConfigMapStore stores the checkpoint as gzipped JSON in a ConfigMap,
+// which must stay under the 1MiB limit of ConfigMaps.
+type ConfigMapStore struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+}
+
+// Load implements Store.
+func (s *ConfigMapStore) Load() (Objects, error) {
+	cm, err := s.Client.CoreV1().ConfigMaps(s.Namespace).Get(s.Name, meta_v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, ok := cm.BinaryData[configMapKey]
+	if !ok {
+		return nil, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var objects Objects
+	if err := json.NewDecoder(r).Decode(&objects); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// Save implements Store.
+func (s *ConfigMapStore) Save(objects Objects) error {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(w).Encode(objects); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	configMaps := s.Client.CoreV1().ConfigMaps(s.Namespace)
+	cm, err := configMaps.Get(s.Name, meta_v1.GetOptions{})
+	if errors.IsNotFound(err) {
+		cm = &api_v1.ConfigMap{
+			ObjectMeta: meta_v1.ObjectMeta{Name: s.Name, Namespace: s.Namespace},
+			BinaryData: map[string][]byte{configMapKey: buf.Bytes()},
+		}
+		_, err = configMaps.Create(cm)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.BinaryData == nil {
+		cm.BinaryData = map[string][]byte{}
+	}
+	cm.BinaryData[configMapKey] = buf.Bytes()
+	_, err = configMaps.Update(cm)
+	return err
+}
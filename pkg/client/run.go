@@ -17,51 +17,192 @@ limitations under the License.
 package client
 
 import (
+	"fmt"
 	"log"
+	"sync/atomic"
 
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/controller"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/alertmanager"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/amqp"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/flock"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/hipchat"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/kafka"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/mattermost"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/mqtt"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/msteam"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/nats"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/opsgenie"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/pagerduty"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/redis"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/slack"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/smtp"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+	"github.com/bitnami-labs/kubewatch/pkg/health"
+	"github.com/bitnami-labs/kubewatch/pkg/server"
 )
 
 // Run runs the event loop processing with given handler
 func Run(conf *config.Config) {
+	if conf.ListenAddress != "" {
+		server.Start(conf.ListenAddress)
+	}
+
+	var initialized int32
+	health.AddReadinessCheck("handlers", func() error {
+		if atomic.LoadInt32(&initialized) == 0 {
+			return fmt.Errorf("not initialized")
+		}
+		return nil
+	})
 
 	var eventHandler = ParseEventHandler(conf)
+	atomic.StoreInt32(&initialized, 1)
 	controller.Start(conf, eventHandler)
+	if err := handlers.Close(eventHandler); err != nil {
+		log.Printf("closing handlers: %v", err)
+	}
 }
 
 // ParseEventHandler returns the respective handler object specified in the config file.
+// Every handler whose configuration section is populated is initialized, and
+// events are either routed to them according to the configured routes or,
+// without routes, fanned out to all of them. Each handler delivers its events
+// in the background, retrying failed deliveries as configured.
 func ParseEventHandler(conf *config.Config) handlers.Handler {
+	var spool *delivery.Spool
+	if conf.Delivery.QueueDir != "" {
+		var err error
+		if spool, err = delivery.OpenSpool(conf.Delivery.QueueDir); err != nil {
+			log.Fatal(err)
+		}
+	}
+	return parseEventHandler(conf, func(name string, h handlers.Handler) handlers.Handler {
+		retry, err := handlers.NewRetry(name, h, conf.Delivery, spool)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return retry
+	})
+}
+
+// TestEventHandler returns the handler object specified in the config file
+// like ParseEventHandler, but delivering events synchronously and without
+// retries so that delivery errors are returned by Handle.
+func TestEventHandler(conf *config.Config) handlers.Handler {
+	return parseEventHandler(conf, func(name string, h handlers.Handler) handlers.Handler {
+		return h
+	})
+}
+
+// EventHandlers returns the initialized handlers specified in the config
+// file by name, delivering events synchronously and without retries.
+func EventHandlers(conf *config.Config) map[string]handlers.Handler {
+	_, destinations := configuredHandlers(conf)
+	for name, h := range destinations {
+		if err := h.Init(conf); err != nil {
+			log.Fatalf("%s: %v", name, err)
+		}
+	}
+	return destinations
+}
+
+func parseEventHandler(conf *config.Config, wrap func(name string, h handlers.Handler) handlers.Handler) handlers.Handler {
+	names, destinations := configuredHandlers(conf)
+	for name, h := range destinations {
+		destinations[name] = wrap(name, h)
+	}
 
 	var eventHandler handlers.Handler
 	switch {
-	case len(conf.Handler.Slack.Channel) > 0 || len(conf.Handler.Slack.Token) > 0:
-		eventHandler = new(slack.Slack)
-	case len(conf.Handler.Hipchat.Room) > 0 || len(conf.Handler.Hipchat.Token) > 0:
-		eventHandler = new(hipchat.Hipchat)
-	case len(conf.Handler.Mattermost.Channel) > 0 || len(conf.Handler.Mattermost.Url) > 0:
-		eventHandler = new(mattermost.Mattermost)
-	case len(conf.Handler.Flock.Url) > 0:
-		eventHandler = new(flock.Flock)
-	case len(conf.Handler.Webhook.Url) > 0:
-		eventHandler = new(webhook.Webhook)
-	case len(conf.Handler.MSTeams.WebhookURL) > 0:
-		eventHandler = new(msteam.MSTeams)
-	case len(conf.Handler.SMTP.Smarthost) > 0 || len(conf.Handler.SMTP.To) > 0:
-		eventHandler = new(smtp.SMTP)
-	default:
+	case len(conf.Routes) > 0:
+		router, err := handlers.NewRouter(conf.Routes, destinations)
+		if err != nil {
+			log.Fatal(err)
+		}
+		eventHandler = router
+	case len(names) == 0:
 		eventHandler = new(handlers.Default)
+	case len(names) == 1:
+		eventHandler = destinations[names[0]]
+	default:
+		eventHandlers := make([]handlers.Handler, 0, len(names))
+		for _, name := range names {
+			eventHandlers = append(eventHandlers, destinations[name])
+		}
+		eventHandler = handlers.NewFanout(eventHandlers...)
 	}
 	if err := eventHandler.Init(conf); err != nil {
 		log.Fatal(err)
 	}
 	return eventHandler
 }
+
+// configuredHandlers returns the uninitialized handlers whose configuration is
+// populated, the ones under handler being named after their type, in order.
+func configuredHandlers(conf *config.Config) ([]string, map[string]handlers.Handler) {
+	var names []string
+	destinations := map[string]handlers.Handler{}
+	add := func(name string, h handlers.Handler) {
+		names = append(names, name)
+		destinations[name] = h
+	}
+	if len(conf.Handler.Slack.Channel) > 0 || len(conf.Handler.Slack.Token) > 0 {
+		add("slack", new(slack.Slack))
+	}
+	if len(conf.Handler.Hipchat.Room) > 0 || len(conf.Handler.Hipchat.Token) > 0 {
+		add("hipchat", new(hipchat.Hipchat))
+	}
+	if len(conf.Handler.Mattermost.Channel) > 0 || len(conf.Handler.Mattermost.Url) > 0 {
+		add("mattermost", new(mattermost.Mattermost))
+	}
+	if len(conf.Handler.Flock.Url) > 0 {
+		add("flock", new(flock.Flock))
+	}
+	if len(conf.Handler.Webhook.Url) > 0 {
+		add("webhook", new(webhook.Webhook))
+	}
+	if len(conf.Handler.MSTeams.WebhookURL) > 0 {
+		add("msteams", new(msteam.MSTeams))
+	}
+	if len(conf.Handler.SMTP.Smarthost) > 0 || len(conf.Handler.SMTP.To) > 0 {
+		add("smtp", new(smtp.SMTP))
+	}
+	if len(conf.Handler.PagerDuty.RoutingKey) > 0 {
+		add("pagerduty", new(pagerduty.PagerDuty))
+	}
+	if len(conf.Handler.Opsgenie.ApiKey) > 0 {
+		add("opsgenie", new(opsgenie.Opsgenie))
+	}
+	if len(conf.Handler.Alertmanager.Url) > 0 {
+		add("alertmanager", new(alertmanager.Alertmanager))
+	}
+	if len(conf.Handler.Kafka.Brokers) > 0 || len(conf.Handler.Kafka.Topic) > 0 {
+		add("kafka", new(kafka.Kafka))
+	}
+	if len(conf.Handler.NATS.Url) > 0 {
+		add("nats", new(nats.NATS))
+	}
+	if len(conf.Handler.AMQP.Url) > 0 {
+		add("amqp", new(amqp.AMQP))
+	}
+	if len(conf.Handler.MQTT.Url) > 0 {
+		add("mqtt", new(mqtt.MQTT))
+	}
+	if len(conf.Handler.Redis.Url) > 0 {
+		add("redis", new(redis.Redis))
+	}
+	for _, h := range conf.Handlers {
+		if _, ok := destinations[h.Name]; ok {
+			log.Fatalf("handler %q: name already used by the %s configuration under handler", h.Name, h.Name)
+		}
+		instance, err := handlers.NewInstance(conf, h)
+		if err != nil {
+			log.Fatal(err)
+		}
+		add(h.Name, instance)
+	}
+	return names, destinations
+}
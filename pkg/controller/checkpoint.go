@@ -0,0 +1,115 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/checkpoint"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/bitnami-labs/kubewatch/pkg/utils"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// loadCheckpoint loads the configured checkpoint, or returns one kept in memory only
+func loadCheckpoint(conf config.Checkpoint, client kubernetes.Interface) (*checkpoint.Checkpoint, time.Duration) {
+	interval, err := conf.SaveInterval()
+	if err != nil {
+		logrus.Fatalf("checkpoint.%v", err)
+	}
+
+	var store checkpoint.Store
+	switch {
+	case conf.File != "":
+		store = &checkpoint.FileStore{Path: conf.File}
+	case conf.ConfigMap != "":
+		namespace := conf.ConfigMapNamespace
+		if namespace == "" {
+			namespace = currentNamespace()
+		}
+		store = &checkpoint.ConfigMapStore{Client: client, Namespace: namespace, Name: conf.ConfigMap}
+	}
+
+	cp, err := checkpoint.New(store)
+	if err != nil {
+		logrus.Fatalf("Error loading checkpoint: %v", err)
+	}
+	return cp, interval
+}
+
+func saveCheckpoint(cp *checkpoint.Checkpoint) {
+	if err := cp.Save(); err != nil {
+		logrus.Errorf("Error saving checkpoint: %v", err)
+	}
+}
+
+// queueMissedDeletes queues a delete event for each object of the checkpoint
+// that is gone from the synced cache, i.e. deleted while kubewatch was down
+func (c *Controller) queueMissedDeletes() {
+	for _, key := range c.checkpoint.Keys(c.resourceType) {
+		if !c.watches(key) {
+			// no longer watched
+			c.checkpoint.Delete(c.resourceType, key)
+			continue
+		}
+		if _, exists, err := c.informer.GetIndexer().GetByKey(key); err != nil || exists {
+			continue
+		}
+		metrics.EventsObserved.WithLabelValues(c.resourceType, "delete").Inc()
+		c.logger.Infof("Processing delete missed while down to %v: %s", c.resourceType, key)
+		c.queue.Add(Event{key: key, eventType: "delete", resourceType: c.resourceType, missed: true, timestamp: time.Now()})
+	}
+}
+
+// notify hands an event over to the event handler, and records the object in
+// the checkpoint once the event is queued for delivery
+func (c *Controller) notify(key, eventType string, e event.Event, obj interface{}) error {
+	err := c.handle(eventType, e, obj)
+	if err == nil {
+		c.record(key, eventType, obj)
+	}
+	return err
+}
+
+// record records the state of an object in the checkpoint
+func (c *Controller) record(key, eventType string, obj interface{}) {
+	switch {
+	case eventType == "delete":
+		c.checkpoint.Delete(c.resourceType, key)
+	case obj != nil:
+		c.checkpoint.Set(c.resourceType, key, c.fingerprint(obj))
+	}
+}
+
+// fingerprint identifies the state of an object, changing exactly when hasChanged reports a change
+func (c *Controller) fingerprint(obj interface{}) string {
+	objectMeta := utils.GetObjectMetaData(obj)
+	if c.generationOnly && objectMeta.Generation != 0 {
+		return fmt.Sprintf("generation:%d", objectMeta.Generation)
+	}
+	fingerprint, err := event.Fingerprint(obj, c.ignoredPaths...)
+	if err != nil {
+		// when in doubt, notify again if it changed at all
+		return "resourceVersion:" + objectMeta.ResourceVersion
+	}
+	return fingerprint
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/checkpoint"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// recorder records the reasons of the events it handles, by name
+type recorder map[string]string
+
+func (r recorder) Init(c *config.Config) error {
+	return nil
+}
+
+func (r recorder) Handle(e event.Event) error {
+	r[e.Name] = e.Reason
+	return nil
+}
+
+func newPod(name, image string, created time.Time) *api_v1.Pod {
+	return &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: meta_v1.NewTime(created),
+		},
+		Spec: api_v1.PodSpec{
+			Containers: []api_v1.Container{{Name: "app", Image: image}},
+		},
+	}
+}
+
+// newTestController returns a pod controller whose cache holds the given pods, without running it
+func newTestController(t *testing.T, cp *checkpoint.Checkpoint, pods ...*api_v1.Pod) (*Controller, recorder) {
+	client := fake.NewSimpleClientset()
+	informer := informers.NewSharedInformerFactory(client, 0).Core().V1().Pods().Informer()
+	for _, pod := range pods {
+		if err := informer.GetIndexer().Add(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handler := recorder{}
+	c := newResourceController(client, handler, informer, "pod", &config.Config{}, cp)
+	c.resumed = cp.Known("pod")
+	c.started = time.Now()
+	return c, handler
+}
+
+func processQueue(t *testing.T, c *Controller) {
+	for c.queue.Len() > 0 {
+		item, _ := c.queue.Get()
+		if err := c.processItem(item.(Event)); err != nil {
+			t.Fatal(err)
+		}
+		c.queue.Done(item)
+	}
+}
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	same := newPod("same", "nginx:1.18", old)
+	changed := newPod("changed", "nginx:1.19", old)
+	created := newPod("created", "nginx:1.18", old.Add(time.Minute))
+
+	cp, err := checkpoint.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, handler := newTestController(t, cp, same, changed, created)
+	cp.Set("pod", "default/same", c.fingerprint(same))
+	cp.Set("pod", "default/changed", c.fingerprint(newPod("changed", "nginx:1.18", old)))
+	cp.Set("pod", "default/deleted", "deadbeef")
+	c.resumed = true
+
+	for _, key := range []string{"default/same", "default/changed", "default/created"} {
+		c.queue.Add(Event{key: key, eventType: "create", resourceType: "pod"})
+	}
+	c.queueMissedDeletes()
+	processQueue(t, c)
+
+	expected := recorder{"changed": "Updated", "created": "Created", "deleted": "Deleted"}
+	if !reflect.DeepEqual(handler, expected) {
+		t.Errorf("expected %v to be notified, got %v", expected, handler)
+	}
+	if keys := cp.Keys("pod"); !reflect.DeepEqual(keys, []string{"default/changed", "default/created", "default/same"}) {
+		t.Errorf("unexpected checkpoint %v", keys)
+	}
+	if fingerprint, _ := cp.Get("pod", "default/changed"); fingerprint != c.fingerprint(changed) {
+		t.Errorf("expected the checkpoint to hold the current state of changed pods")
+	}
+}
+
+func TestStartWithoutCheckpoint(t *testing.T) {
+	existing := newPod("existing", "nginx:1.18", time.Now().Add(-time.Hour))
+
+	cp, err := checkpoint.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, handler := newTestController(t, cp, existing)
+
+	c.queue.Add(Event{key: "default/existing", eventType: "create", resourceType: "pod"})
+	processQueue(t, c)
+
+	if len(handler) != 0 {
+		t.Errorf("expected pods created before the start not to be notified, got %v", handler)
+	}
+	if _, ok := cp.Get("pod", "default/existing"); !ok {
+		t.Errorf("expected pods created before the start to be recorded")
+	}
+}
+
+// sink passes on the events it handles
+type sink chan event.Event
+
+func (s sink) Init(c *config.Config) error {
+	return nil
+}
+
+func (s sink) Handle(e event.Event) error {
+	s <- e
+	return nil
+}
+
+func TestLiveEventNames(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	informer := informers.NewSharedInformerFactory(client, 0).Core().V1().Pods().Informer()
+	cp, err := checkpoint.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := make(sink, 2)
+	c := newResourceController(client, handler, informer, "pod", &config.Config{}, cp)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go c.Run(stopCh)
+	for deadline := time.Now().Add(5 * time.Second); !cp.Known("pod"); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("cache not synced")
+		}
+	}
+
+	// the events of an object are named alike, live deletes included
+	pod := newPod("foo", "nginx:1.18", time.Now().Add(time.Minute))
+	for _, reason := range []string{"Created", "Deleted"} {
+		if reason == "Created" {
+			_, err = client.CoreV1().Pods("default").Create(pod)
+		} else {
+			err = client.CoreV1().Pods("default").Delete("foo", nil)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case e := <-handler:
+			if e.Reason != reason || e.Name != "foo" || e.Namespace != "default" {
+				t.Errorf("expected %s event for default/foo, got %s event for %s/%s", reason, e.Reason, e.Namespace, e.Name)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the %s event", reason)
+		}
+	}
+}
+
+func TestRunProcessesQueueBeforeStopping(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	informer := informers.NewSharedInformerFactory(client, 0).Core().V1().Pods().Informer()
+	cp, err := checkpoint.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := recorder{}
+	c := newResourceController(client, handler, informer, "pod", &config.Config{}, cp)
+
+	stopCh := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		c.Run(stopCh)
+		close(stopped)
+	}()
+	// the pods are tracked once the cache synced
+	for deadline := time.Now().Add(5 * time.Second); !cp.Known("pod"); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("cache not synced")
+		}
+	}
+	for i := 0; i < 100; i++ {
+		c.queue.Add(Event{key: fmt.Sprintf("default/gone-%d", i), eventType: "delete", resourceType: "pod"})
+	}
+	close(stopCh)
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return once stopped")
+	}
+	if len(handler) != 100 {
+		t.Errorf("expected the 100 queued events processed before Run returned, got %d", len(handler))
+	}
+}
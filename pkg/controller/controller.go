@@ -17,16 +17,23 @@ limitations under the License.
 package controller
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"regexp"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/checkpoint"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/filter"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers"
+	"github.com/bitnami-labs/kubewatch/pkg/health"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
 	"github.com/bitnami-labs/kubewatch/pkg/utils"
 	"github.com/sirupsen/logrus"
 
@@ -37,9 +44,13 @@ import (
 	rbac_v1beta1 "k8s.io/api/rbac/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -48,7 +59,8 @@ import (
 
 const maxRetries = 5
 
-var serverStartTime time.Time
+// workerTimeout is how long a worker may process a single event before being considered wedged
+const workerTimeout = 5 * time.Minute
 
 // Event indicate the informerEvent
 type Event struct {
@@ -56,6 +68,14 @@ type Event struct {
 	eventType    string
 	namespace    string
 	resourceType string
+	// old and new versions of the object, set for updates;
+	// deletes carry the last known state as oldObj
+	oldObj interface{}
+	newObj interface{}
+	// missed is set for deletes that happened while kubewatch was down
+	missed bool
+	// time at which the change was observed
+	timestamp time.Time
 }
 
 // Controller object
@@ -65,29 +85,141 @@ type Controller struct {
 	queue        workqueue.RateLimitingInterface
 	informer     cache.SharedIndexInformer
 	eventHandler handlers.Handler
+	resourceType string
+	activity     *health.Activity
+
+	// objects already notified, and whether they were notified by a
+	// previous run; otherwise objects created before started are skipped
+	checkpoint *checkpoint.Checkpoint
+	resumed    bool
+	started    time.Time
+
+	// update change detection settings
+	ignoredPaths   []string
+	generationOnly bool
+
+	includeObjects bool
+
+	namespaces *namespaceFilter
+	filter     *filter.Filter
+}
+
+// namespaceFilter selects the namespaces whose objects are reported
+type namespaceFilter struct {
+	allowed  map[string]bool
+	excluded []*regexp.Regexp
+}
+
+func newNamespaceFilter(conf *config.Config) *namespaceFilter {
+	f := &namespaceFilter{}
+	if len(conf.Namespaces) > 0 {
+		f.allowed = map[string]bool{}
+		for _, ns := range conf.Namespaces {
+			f.allowed[ns] = true
+		}
+	}
+	for _, p := range conf.ExcludeNamespaces {
+		re, err := config.NamespacePattern(p)
+		if err != nil {
+			logrus.Errorf("Ignoring namespace exclusion %q: %v", p, err)
+			continue
+		}
+		f.excluded = append(f.excluded, re)
+	}
+	return f
+}
+
+// matches reports whether objects of a namespace are reported.
+// Cluster-scoped objects are always reported.
+func (f *namespaceFilter) matches(namespace string) bool {
+	if namespace == "" {
+		return true
+	}
+	if f.allowed != nil && !f.allowed[namespace] {
+		return false
+	}
+	for _, re := range f.excluded {
+		if re.MatchString(namespace) {
+			return false
+		}
+	}
+	return true
+}
+
+// watches reports whether the object identified by key is in a watched namespace
+func (c *Controller) watches(key string) bool {
+	namespace, _, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return true
+	}
+	return c.namespaces.matches(namespace)
 }
 
-// Start prepares watchers and run their controllers, then waits for process termination signals
+// Start prepares watchers and run their controllers until process termination signals.
+// With leader election enabled, watchers only run while holding the lease.
 func Start(conf *config.Config, eventHandler handlers.Handler) {
 	var kubeClient kubernetes.Interface
+	var dynamicClient dynamic.Interface
 
 	if _, err := rest.InClusterConfig(); err != nil {
 		kubeClient = utils.GetClientOutOfCluster()
+		dynamicClient = utils.GetDynamicClientOutOfCluster()
 	} else {
 		kubeClient = utils.GetClient()
+		dynamicClient = utils.GetDynamicClient()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		sigterm := make(chan os.Signal, 1)
+		signal.Notify(sigterm, syscall.SIGTERM)
+		signal.Notify(sigterm, syscall.SIGINT)
+		<-sigterm
+		cancel()
+	}()
+
+	if !conf.LeaderElection.Enabled {
+		runWatchers(ctx, conf, kubeClient, dynamicClient, eventHandler)
+		return
+	}
+	runLeaderElection(ctx, conf.LeaderElection, kubeClient, func(ctx context.Context) {
+		runWatchers(ctx, conf, kubeClient, dynamicClient, eventHandler)
+	})
+}
+
+// runWatchers prepares watchers and run their controllers until ctx is done
+func runWatchers(ctx context.Context, conf *config.Config, kubeClient kubernetes.Interface, dynamicClient dynamic.Interface, eventHandler handlers.Handler) {
+	// Loaded only now, as the previous leader may have saved it until then
+	cp, interval := loadCheckpoint(conf.Checkpoint, kubeClient)
+	go cp.Run(ctx, interval)
+	// Deferred first, so that it runs once the controllers are stopped
+	defer saveCheckpoint(cp)
+
+	// The controllers stop with ctx, once they processed the events they
+	// queued, and are waited for so that the checkpoint records them
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	run := func(c *Controller) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Run(ctx.Done())
+		}()
 	}
 
+	namespace := watchedNamespace(conf)
+
 	// Adding Default Critical Alerts
 	// For Capturing Critical Event NodeNotReady in Nodes
 	nodeNotReadyInformer := cache.NewSharedIndexInformer(
 		&cache.ListWatch{
 			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
 				options.FieldSelector = "involvedObject.kind=Node,type=Normal,reason=NodeNotReady"
-				return kubeClient.CoreV1().Events(conf.Namespace).List(options)
+				return kubeClient.CoreV1().Events(namespace).List(options)
 			},
 			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
 				options.FieldSelector = "involvedObject.kind=Node,type=Normal,reason=NodeNotReady"
-				return kubeClient.CoreV1().Events(conf.Namespace).Watch(options)
+				return kubeClient.CoreV1().Events(namespace).Watch(options)
 			},
 		},
 		&api_v1.Event{},
@@ -95,22 +227,19 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 		cache.Indexers{},
 	)
 
-	nodeNotReadyController := newResourceController(kubeClient, eventHandler, nodeNotReadyInformer, "NodeNotReady")
-	stopNodeNotReadyCh := make(chan struct{})
-	defer close(stopNodeNotReadyCh)
-
-	go nodeNotReadyController.Run(stopNodeNotReadyCh)
+	nodeNotReadyController := newResourceController(kubeClient, eventHandler, nodeNotReadyInformer, "NodeNotReady", conf, cp)
+	run(nodeNotReadyController)
 
 	// For Capturing Critical Event NodeReady in Nodes
 	nodeReadyInformer := cache.NewSharedIndexInformer(
 		&cache.ListWatch{
 			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
 				options.FieldSelector = "involvedObject.kind=Node,type=Normal,reason=NodeReady"
-				return kubeClient.CoreV1().Events(conf.Namespace).List(options)
+				return kubeClient.CoreV1().Events(namespace).List(options)
 			},
 			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
 				options.FieldSelector = "involvedObject.kind=Node,type=Normal,reason=NodeReady"
-				return kubeClient.CoreV1().Events(conf.Namespace).Watch(options)
+				return kubeClient.CoreV1().Events(namespace).Watch(options)
 			},
 		},
 		&api_v1.Event{},
@@ -118,22 +247,19 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 		cache.Indexers{},
 	)
 
-	nodeReadyController := newResourceController(kubeClient, eventHandler, nodeReadyInformer, "NodeReady")
-	stopNodeReadyCh := make(chan struct{})
-	defer close(stopNodeReadyCh)
-
-	go nodeReadyController.Run(stopNodeReadyCh)
+	nodeReadyController := newResourceController(kubeClient, eventHandler, nodeReadyInformer, "NodeReady", conf, cp)
+	run(nodeReadyController)
 
 	// For Capturing Critical Event NodeRebooted in Nodes
 	nodeRebootedInformer := cache.NewSharedIndexInformer(
 		&cache.ListWatch{
 			ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
 				options.FieldSelector = "involvedObject.kind=Node,type=Warning,reason=Rebooted"
-				return kubeClient.CoreV1().Events(conf.Namespace).List(options)
+				return kubeClient.CoreV1().Events(namespace).List(options)
 			},
 			WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
 				options.FieldSelector = "involvedObject.kind=Node,type=Warning,reason=Rebooted"
-				return kubeClient.CoreV1().Events(conf.Namespace).Watch(options)
+				return kubeClient.CoreV1().Events(namespace).Watch(options)
 			},
 		},
 		&api_v1.Event{},
@@ -141,21 +267,21 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 		cache.Indexers{},
 	)
 
-	nodeRebootedController := newResourceController(kubeClient, eventHandler, nodeRebootedInformer, "NodeRebooted")
-	stopNodeRebootedCh := make(chan struct{})
-	defer close(stopNodeRebootedCh)
-
-	go nodeRebootedController.Run(stopNodeRebootedCh)
+	nodeRebootedController := newResourceController(kubeClient, eventHandler, nodeRebootedInformer, "NodeRebooted", conf, cp)
+	run(nodeRebootedController)
 
 	// User Configured Events
 	if conf.Resource.Pod {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["pod"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Pods(conf.Namespace).List(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().Pods(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Pods(conf.Namespace).Watch(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().Pods(namespace).Watch(options)
 				},
 			},
 			&api_v1.Pod{},
@@ -163,22 +289,19 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "pod")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "pod", conf, cp)
+		run(c)
 
 		// For Capturing CrashLoopBackOff Events in pods
 		backoffInformer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
 					options.FieldSelector = "involvedObject.kind=Pod,type=Warning,reason=BackOff"
-					return kubeClient.CoreV1().Events(conf.Namespace).List(options)
+					return kubeClient.CoreV1().Events(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
 					options.FieldSelector = "involvedObject.kind=Pod,type=Warning,reason=BackOff"
-					return kubeClient.CoreV1().Events(conf.Namespace).Watch(options)
+					return kubeClient.CoreV1().Events(namespace).Watch(options)
 				},
 			},
 			&api_v1.Event{},
@@ -186,22 +309,22 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		backoffcontroller := newResourceController(kubeClient, eventHandler, backoffInformer, "Backoff")
-		stopBackoffCh := make(chan struct{})
-		defer close(stopBackoffCh)
-
-		go backoffcontroller.Run(stopBackoffCh)
+		backoffcontroller := newResourceController(kubeClient, eventHandler, backoffInformer, "Backoff", conf, cp)
+		run(backoffcontroller)
 
 	}
 
 	if conf.Resource.DaemonSet {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["daemon set"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AppsV1().DaemonSets(conf.Namespace).List(options)
+					tweakListOptions(&options)
+					return kubeClient.AppsV1().DaemonSets(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AppsV1().DaemonSets(conf.Namespace).Watch(options)
+					tweakListOptions(&options)
+					return kubeClient.AppsV1().DaemonSets(namespace).Watch(options)
 				},
 			},
 			&apps_v1.DaemonSet{},
@@ -209,21 +332,21 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "daemon set")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "daemon set", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.ReplicaSet {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["replica set"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AppsV1().ReplicaSets(conf.Namespace).List(options)
+					tweakListOptions(&options)
+					return kubeClient.AppsV1().ReplicaSets(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AppsV1().ReplicaSets(conf.Namespace).Watch(options)
+					tweakListOptions(&options)
+					return kubeClient.AppsV1().ReplicaSets(namespace).Watch(options)
 				},
 			},
 			&apps_v1.ReplicaSet{},
@@ -231,21 +354,21 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "replica set")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "replica set", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.Services {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["service"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Services(conf.Namespace).List(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().Services(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Services(conf.Namespace).Watch(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().Services(namespace).Watch(options)
 				},
 			},
 			&api_v1.Service{},
@@ -253,21 +376,21 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "service")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "service", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.Deployment {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["deployment"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.AppsV1().Deployments(conf.Namespace).List(options)
+					tweakListOptions(&options)
+					return kubeClient.AppsV1().Deployments(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.AppsV1().Deployments(conf.Namespace).Watch(options)
+					tweakListOptions(&options)
+					return kubeClient.AppsV1().Deployments(namespace).Watch(options)
 				},
 			},
 			&apps_v1.Deployment{},
@@ -275,20 +398,20 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "deployment")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "deployment", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.Namespace {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["namespace"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					tweakListOptions(&options)
 					return kubeClient.CoreV1().Namespaces().List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					tweakListOptions(&options)
 					return kubeClient.CoreV1().Namespaces().Watch(options)
 				},
 			},
@@ -297,21 +420,21 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "namespace")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "namespace", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.ReplicationController {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["replication controller"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ReplicationControllers(conf.Namespace).List(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().ReplicationControllers(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ReplicationControllers(conf.Namespace).Watch(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().ReplicationControllers(namespace).Watch(options)
 				},
 			},
 			&api_v1.ReplicationController{},
@@ -319,21 +442,21 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "replication controller")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "replication controller", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.Job {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["job"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.BatchV1().Jobs(conf.Namespace).List(options)
+					tweakListOptions(&options)
+					return kubeClient.BatchV1().Jobs(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.BatchV1().Jobs(conf.Namespace).Watch(options)
+					tweakListOptions(&options)
+					return kubeClient.BatchV1().Jobs(namespace).Watch(options)
 				},
 			},
 			&batch_v1.Job{},
@@ -341,20 +464,20 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "job")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "job", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.Node {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["node"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					tweakListOptions(&options)
 					return kubeClient.CoreV1().Nodes().List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					tweakListOptions(&options)
 					return kubeClient.CoreV1().Nodes().Watch(options)
 				},
 			},
@@ -363,21 +486,21 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "node")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "node", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.ServiceAccount {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["service account"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ServiceAccounts(conf.Namespace).List(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().ServiceAccounts(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ServiceAccounts(conf.Namespace).Watch(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().ServiceAccounts(namespace).Watch(options)
 				},
 			},
 			&api_v1.ServiceAccount{},
@@ -385,20 +508,20 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "service account")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "service account", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.ClusterRole {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["cluster role"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					tweakListOptions(&options)
 					return kubeClient.RbacV1beta1().ClusterRoles().List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					tweakListOptions(&options)
 					return kubeClient.RbacV1beta1().ClusterRoles().Watch(options)
 				},
 			},
@@ -407,20 +530,20 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "cluster role")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "cluster role", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.PersistentVolume {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["persistent volume"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+					tweakListOptions(&options)
 					return kubeClient.CoreV1().PersistentVolumes().List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+					tweakListOptions(&options)
 					return kubeClient.CoreV1().PersistentVolumes().Watch(options)
 				},
 			},
@@ -429,21 +552,21 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "persistent volume")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "persistent volume", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.Secret {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["secret"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().Secrets(conf.Namespace).List(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().Secrets(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().Secrets(conf.Namespace).Watch(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().Secrets(namespace).Watch(options)
 				},
 			},
 			&api_v1.Secret{},
@@ -451,21 +574,21 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "secret")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "secret", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.ConfigMap {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["configmap"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.CoreV1().ConfigMaps(conf.Namespace).List(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().ConfigMaps(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.CoreV1().ConfigMaps(conf.Namespace).Watch(options)
+					tweakListOptions(&options)
+					return kubeClient.CoreV1().ConfigMaps(namespace).Watch(options)
 				},
 			},
 			&api_v1.ConfigMap{},
@@ -473,21 +596,21 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "configmap")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
-
-		go c.Run(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "configmap", conf, cp)
+		run(c)
 	}
 
 	if conf.Resource.Ingress {
+		tweakListOptions := newTweakListOptions(conf.ResourceOptions["ingress"])
 		informer := cache.NewSharedIndexInformer(
 			&cache.ListWatch{
 				ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
-					return kubeClient.ExtensionsV1beta1().Ingresses(conf.Namespace).List(options)
+					tweakListOptions(&options)
+					return kubeClient.ExtensionsV1beta1().Ingresses(namespace).List(options)
 				},
 				WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
-					return kubeClient.ExtensionsV1beta1().Ingresses(conf.Namespace).Watch(options)
+					tweakListOptions(&options)
+					return kubeClient.ExtensionsV1beta1().Ingresses(namespace).Watch(options)
 				},
 			},
 			&ext_v1beta1.Ingress{},
@@ -495,47 +618,169 @@ func Start(conf *config.Config, eventHandler handlers.Handler) {
 			cache.Indexers{},
 		)
 
-		c := newResourceController(kubeClient, eventHandler, informer, "ingress")
-		stopCh := make(chan struct{})
-		defer close(stopCh)
+		c := newResourceController(kubeClient, eventHandler, informer, "ingress", conf, cp)
+		run(c)
+	}
+
+	// Resources watched through the dynamic client, including custom resources
+	for _, r := range conf.CustomResources {
+		gvr, err := utils.ParseGroupVersionResource(r.Resource)
+		if err != nil {
+			logrus.Errorf("Skipping custom resource: %v", err)
+			continue
+		}
+		apiResource, err := lookupAPIResource(kubeClient.Discovery(), gvr)
+		if err != nil {
+			logrus.Errorf("Skipping custom resource %s: %v", r.Resource, err)
+			continue
+		}
 
-		go c.Run(stopCh)
+		resourceType := strings.ToLower(apiResource.Kind)
+		resourceNamespace := namespace
+		if !apiResource.Namespaced {
+			resourceNamespace = meta_v1.NamespaceAll
+		}
+		informer := dynamicinformer.NewFilteredDynamicInformer(
+			dynamicClient,
+			gvr,
+			resourceNamespace,
+			0, //Skip resync
+			cache.Indexers{},
+			newTweakListOptions(conf.ResourceOptions[resourceType]),
+		).Informer()
+
+		c := newResourceController(kubeClient, eventHandler, informer, resourceType, conf, cp)
+		run(c)
 	}
 
-	sigterm := make(chan os.Signal, 1)
-	signal.Notify(sigterm, syscall.SIGTERM)
-	signal.Notify(sigterm, syscall.SIGINT)
-	<-sigterm
+	<-ctx.Done()
 }
 
-func newResourceController(client kubernetes.Interface, eventHandler handlers.Handler, informer cache.SharedIndexInformer, resourceType string) *Controller {
-	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
-	var newEvent Event
-	var err error
+// watchedNamespace returns the namespace the informers watch: a single
+// namespace can be watched directly, several are filtered centrally
+func watchedNamespace(conf *config.Config) string {
+	switch len(conf.Namespaces) {
+	case 0:
+		return conf.Namespace
+	case 1:
+		return conf.Namespaces[0]
+	}
+	return meta_v1.NamespaceAll
+}
+
+// newTweakListOptions returns a function narrowing List and Watch calls with the configured selectors
+func newTweakListOptions(opts config.ResourceOptions) func(*meta_v1.ListOptions) {
+	return func(options *meta_v1.ListOptions) {
+		if opts.LabelSelector != "" {
+			options.LabelSelector = opts.LabelSelector
+		}
+		if opts.FieldSelector != "" {
+			options.FieldSelector = opts.FieldSelector
+		}
+	}
+}
+
+// lookupAPIResource asks the API server's discovery endpoint for the kind and scope of a resource
+func lookupAPIResource(client discovery.DiscoveryInterface, gvr schema.GroupVersionResource) (*meta_v1.APIResource, error) {
+	resources, err := client.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+	if err != nil {
+		return nil, err
+	}
+	for i := range resources.APIResources {
+		if resources.APIResources[i].Name == gvr.Resource {
+			return &resources.APIResources[i], nil
+		}
+	}
+	return nil, fmt.Errorf("resource %q not found in %s", gvr.Resource, gvr.GroupVersion())
+}
+
+func newResourceController(client kubernetes.Interface, eventHandler handlers.Handler, informer cache.SharedIndexInformer, resourceType string, conf *config.Config, cp *checkpoint.Checkpoint) *Controller {
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), resourceType)
+	opts := conf.ResourceOptions[resourceType]
+	rules := make([]filter.Rule, 0, len(conf.Filters))
+	for _, f := range conf.Filters {
+		rules = append(rules, filter.Rule{Expression: f.Expression, Exclude: f.Exclude()})
+	}
+	eventFilter, err := filter.New(rules)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	c := &Controller{
+		logger:         logrus.WithField("pkg", "kubewatch-"+resourceType),
+		clientset:      client,
+		informer:       informer,
+		queue:          queue,
+		eventHandler:   eventHandler,
+		resourceType:   resourceType,
+		activity:       &health.Activity{Timeout: workerTimeout},
+		checkpoint:     cp,
+		ignoredPaths:   append(append([]string{}, conf.IgnorePaths...), opts.IgnorePaths...),
+		generationOnly: opts.GenerationOnly,
+		includeObjects: conf.IncludeObjects,
+		namespaces:     newNamespaceFilter(conf),
+		filter:         eventFilter,
+	}
+
 	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
+			var newEvent Event
+			var err error
 			newEvent.key, err = cache.MetaNamespaceKeyFunc(obj)
 			newEvent.eventType = "create"
+			newEvent.timestamp = time.Now()
 			newEvent.resourceType = resourceType
+			metrics.EventsObserved.WithLabelValues(resourceType, newEvent.eventType).Inc()
+			if err == nil && !c.watches(newEvent.key) {
+				metrics.EventsFiltered.WithLabelValues(resourceType, newEvent.eventType, metrics.FilteredNamespace).Inc()
+				return
+			}
 			logrus.WithField("pkg", "kubewatch-"+resourceType).Infof("Processing add to %v: %s", resourceType, newEvent.key)
 			if err == nil {
 				queue.Add(newEvent)
 			}
 		},
 		UpdateFunc: func(old, new interface{}) {
+			var newEvent Event
+			var err error
 			newEvent.key, err = cache.MetaNamespaceKeyFunc(old)
 			newEvent.eventType = "update"
+			newEvent.timestamp = time.Now()
 			newEvent.resourceType = resourceType
+			metrics.EventsObserved.WithLabelValues(resourceType, newEvent.eventType).Inc()
+			newEvent.oldObj = old
+			newEvent.newObj = new
+			if err == nil && !c.watches(newEvent.key) {
+				metrics.EventsFiltered.WithLabelValues(resourceType, newEvent.eventType, metrics.FilteredNamespace).Inc()
+				return
+			}
+			if !c.hasChanged(old, new) {
+				metrics.EventsFiltered.WithLabelValues(resourceType, newEvent.eventType, metrics.FilteredUnchanged).Inc()
+				logrus.WithField("pkg", "kubewatch-"+resourceType).Debugf("Skipping no-op update to %v: %s", resourceType, newEvent.key)
+				return
+			}
 			logrus.WithField("pkg", "kubewatch-"+resourceType).Infof("Processing update to %v: %s", resourceType, newEvent.key)
 			if err == nil {
 				queue.Add(newEvent)
 			}
 		},
 		DeleteFunc: func(obj interface{}) {
+			var newEvent Event
+			var err error
 			newEvent.key, err = cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 			newEvent.eventType = "delete"
+			newEvent.timestamp = time.Now()
 			newEvent.resourceType = resourceType
-			newEvent.namespace = utils.GetObjectMetaData(obj).Namespace
+			metrics.EventsObserved.WithLabelValues(resourceType, newEvent.eventType).Inc()
+			if err == nil && !c.watches(newEvent.key) {
+				metrics.EventsFiltered.WithLabelValues(resourceType, newEvent.eventType, metrics.FilteredNamespace).Inc()
+				return
+			}
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				newEvent.oldObj = tombstone.Obj
+			} else {
+				newEvent.oldObj = obj
+			}
+			newEvent.namespace = utils.GetObjectMetaData(newEvent.oldObj).Namespace
 			logrus.WithField("pkg", "kubewatch-"+resourceType).Infof("Processing delete to %v: %s", resourceType, newEvent.key)
 			if err == nil {
 				queue.Add(newEvent)
@@ -543,22 +788,47 @@ func newResourceController(client kubernetes.Interface, eventHandler handlers.Ha
 		},
 	})
 
-	return &Controller{
-		logger:       logrus.WithField("pkg", "kubewatch-"+resourceType),
-		clientset:    client,
-		informer:     informer,
-		queue:        queue,
-		eventHandler: eventHandler,
+	return c
+}
+
+// hasChanged reports whether an update modified anything besides the ignored fields,
+// or only the generation when generation-based change detection is enabled.
+func (c *Controller) hasChanged(old, new interface{}) bool {
+	if c.generationOnly {
+		oldGeneration := utils.GetObjectMetaData(old).Generation
+		newGeneration := utils.GetObjectMetaData(new).Generation
+		if oldGeneration != 0 || newGeneration != 0 {
+			return oldGeneration != newGeneration
+		}
 	}
+
+	diff, err := event.Diff(old, new, c.ignoredPaths...)
+	if err != nil {
+		// when in doubt, notify
+		return true
+	}
+	return len(diff) > 0
 }
 
 // Run starts the kubewatch controller
 func (c *Controller) Run(stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer c.queue.ShutDown()
+	// the worker returns once the events still queued are processed
+	go func() {
+		<-stopCh
+		c.queue.ShutDown()
+	}()
 
 	c.logger.Info("Starting kubewatch controller")
-	serverStartTime = time.Now().Local()
+	c.resumed = c.checkpoint.Known(c.resourceType)
+	c.started = time.Now()
+
+	synced := metrics.InformerSynced.WithLabelValues(c.resourceType)
+	synced.Set(0)
+	defer synced.Set(0)
+	defer health.AddReadinessCheck("informer "+c.resourceType, c.checkSynced)()
+	defer health.AddLivenessCheck("worker "+c.resourceType, c.activity.Check)()
 
 	go c.informer.Run(stopCh)
 
@@ -566,6 +836,9 @@ func (c *Controller) Run(stopCh <-chan struct{}) {
 		utilruntime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
 		return
 	}
+	synced.Set(1)
+	c.queueMissedDeletes()
+	c.checkpoint.Track(c.resourceType)
 
 	c.logger.Info("Kubewatch controller synced and ready")
 
@@ -577,6 +850,13 @@ func (c *Controller) HasSynced() bool {
 	return c.informer.HasSynced()
 }
 
+func (c *Controller) checkSynced() error {
+	if !c.HasSynced() {
+		return fmt.Errorf("cache not synced")
+	}
+	return nil
+}
+
 // LastSyncResourceVersion is required for the cache.Controller interface.
 func (c *Controller) LastSyncResourceVersion() string {
 	return c.informer.LastSyncResourceVersion()
@@ -595,7 +875,14 @@ func (c *Controller) processNextItem() bool {
 		return false
 	}
 	defer c.queue.Done(newEvent)
+	c.activity.Start()
+	defer c.activity.Done()
 	err := c.processItem(newEvent.(Event))
+	if err != nil {
+		metrics.EventsFailed.WithLabelValues(c.resourceType).Inc()
+	}
+	// the handlers retry failed deliveries themselves, in order, so the errors
+	// left are about queueing the event, e.g. storing it in the spool
 	if err == nil {
 		// No error, reset the ratelimit counters
 		c.queue.Forget(newEvent)
@@ -619,6 +906,7 @@ func (c *Controller) processNextItem() bool {
 */
 
 func (c *Controller) processItem(newEvent Event) error {
+	key := newEvent.key
 	obj, _, err := c.informer.GetIndexer().GetByKey(newEvent.key)
 	if err != nil {
 		return fmt.Errorf("Error fetching object with key %s from store: %v", newEvent.key, err)
@@ -626,22 +914,42 @@ func (c *Controller) processItem(newEvent Event) error {
 	// get object's metedata
 	objectMeta := utils.GetObjectMetaData(obj)
 
+	// objects listed after a restart are compared with the checkpoint
+	if newEvent.eventType == "create" && obj != nil {
+		if fingerprint, ok := c.checkpoint.Get(c.resourceType, key); ok {
+			if fingerprint == c.fingerprint(obj) {
+				// notified before the restart
+				return nil
+			}
+			// updated while kubewatch was down
+			newEvent.eventType = "update"
+			newEvent.newObj = obj
+		}
+	}
+	if newEvent.eventType == "delete" && newEvent.missed {
+		if _, ok := c.checkpoint.Get(c.resourceType, key); !ok {
+			// the delete was observed meanwhile
+			return nil
+		}
+	}
+
 	// hold status type for default critical alerts
 	var status string
 
-	// namespace retrived from event key incase namespace value is empty
-	if newEvent.namespace == "" && strings.Contains(newEvent.key, "/") {
-		substring := strings.Split(newEvent.key, "/")
-		newEvent.namespace = substring[0]
-		newEvent.key = substring[1]
+	// name retrieved from the event key, and namespace too in case it is
+	// empty, so that the events of an object are named alike whatever their type
+	if namespace, name, err := cache.SplitMetaNamespaceKey(newEvent.key); err == nil {
+		if newEvent.namespace == "" {
+			newEvent.namespace = namespace
+		}
+		newEvent.key = name
 	}
 
 	// process events based on its type
 	switch newEvent.eventType {
 	case "create":
-		// compare CreationTimestamp and serverStartTime and alert only on latest events
-		// Could be Replaced by using Delta or DeltaFIFO
-		if objectMeta.CreationTimestamp.Sub(serverStartTime).Seconds() > 0 {
+		// without checkpoint, alert only on objects created after the controller started
+		if obj != nil && (c.resumed || objectMeta.CreationTimestamp.After(c.started)) {
 			switch newEvent.resourceType {
 			case "NodeNotReady":
 				status = "Danger"
@@ -660,14 +968,21 @@ func (c *Controller) processItem(newEvent Event) error {
 				Kind:      newEvent.resourceType,
 				Status:    status,
 				Reason:    "Created",
+				Timestamp: newEvent.timestamp,
 			}
-			c.eventHandler.Handle(kbEvent)
-			return nil
+			c.describe(&kbEvent, obj, nil, obj)
+			return c.notify(key, newEvent.eventType, kbEvent, obj)
 		}
+		c.record(key, newEvent.eventType, obj)
 	case "update":
-		/* TODOs
-		- enahace update event processing in such a way that, it send alerts about what got changed.
-		*/
+		// updates missed while kubewatch was down have no old version to compare with
+		var diff []event.Change
+		if newEvent.oldObj != nil {
+			diff, err = event.Diff(newEvent.oldObj, newEvent.newObj, c.ignoredPaths...)
+			if err != nil {
+				c.logger.Errorf("Error computing changes of %s: %v", newEvent.key, err)
+			}
+		}
 		switch newEvent.resourceType {
 		case "Backoff":
 			status = "Danger"
@@ -680,9 +995,11 @@ func (c *Controller) processItem(newEvent Event) error {
 			Kind:      newEvent.resourceType,
 			Status:    status,
 			Reason:    "Updated",
+			Timestamp: newEvent.timestamp,
+			Diff:      diff,
 		}
-		c.eventHandler.Handle(kbEvent)
-		return nil
+		c.describe(&kbEvent, newEvent.newObj, newEvent.oldObj, newEvent.newObj)
+		return c.notify(key, newEvent.eventType, kbEvent, newEvent.newObj)
 	case "delete":
 		kbEvent := event.Event{
 			Name:      newEvent.key,
@@ -690,9 +1007,46 @@ func (c *Controller) processItem(newEvent Event) error {
 			Kind:      newEvent.resourceType,
 			Status:    "Danger",
 			Reason:    "Deleted",
+			Timestamp: newEvent.timestamp,
 		}
-		c.eventHandler.Handle(kbEvent)
-		return nil
+		c.describe(&kbEvent, newEvent.oldObj, newEvent.oldObj, nil)
+		return c.notify(key, newEvent.eventType, kbEvent, newEvent.oldObj)
 	}
 	return nil
 }
+
+// describe completes an event with the metadata of the object it is about,
+// and with its old and new versions when enabled
+func (c *Controller) describe(e *event.Event, obj, oldObj, newObj interface{}) {
+	objectMeta := utils.GetObjectMetaData(obj)
+	e.UID = string(objectMeta.UID)
+	e.APIVersion = utils.GetObjectGroupVersionKind(obj).GroupVersion().String()
+	e.ResourceVersion = objectMeta.ResourceVersion
+	e.Labels = objectMeta.Labels
+	e.Annotations = objectMeta.Annotations
+	e.OwnerReferences = objectMeta.OwnerReferences
+	if ev, ok := obj.(*api_v1.Event); ok {
+		e.InvolvedObject = &ev.InvolvedObject
+	}
+
+	if !c.includeObjects {
+		return
+	}
+	var err error
+	if e.OldObject, err = event.ObjectJSON(oldObj); err != nil {
+		c.logger.Errorf("Error encoding %s: %v", e.Name, err)
+	}
+	if e.Object, err = event.ObjectJSON(newObj); err != nil {
+		c.logger.Errorf("Error encoding %s: %v", e.Name, err)
+	}
+}
+
+// handle hands an event over to the event handler, unless it is filtered out
+func (c *Controller) handle(eventType string, e event.Event, obj interface{}) error {
+	if !c.filter.Matches(e, obj) {
+		metrics.EventsFiltered.WithLabelValues(c.resourceType, eventType, metrics.FilteredFilter).Inc()
+		c.logger.Debugf("Filtered out %s event for %s", e.Reason, e.Name)
+		return nil
+	}
+	return c.eventHandler.Handle(e)
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDeployment(generation int64, replicas int32, labels map[string]string) *apps_v1.Deployment {
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:       "foo",
+			Namespace:  "new",
+			Generation: generation,
+			Labels:     labels,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: &replicas,
+		},
+	}
+}
+
+func TestHasChanged(t *testing.T) {
+	statusOnly := newDeployment(1, 1, nil)
+	statusOnly.Status.ObservedGeneration = 1
+	statusOnly.ResourceVersion = "2"
+
+	var Tests = []struct {
+		name       string
+		controller *Controller
+		old, new   *apps_v1.Deployment
+		changed    bool
+	}{
+		{"resync", &Controller{}, newDeployment(1, 1, nil), newDeployment(1, 1, nil), false},
+		{"status churn", &Controller{}, newDeployment(1, 1, nil), statusOnly, false},
+		{"spec change", &Controller{}, newDeployment(1, 1, nil), newDeployment(2, 3, nil), true},
+		{"label change", &Controller{}, newDeployment(1, 1, nil), newDeployment(1, 1, map[string]string{"a": "b"}), true},
+		{"ignored label change", &Controller{ignoredPaths: []string{"metadata.labels"}}, newDeployment(1, 1, nil), newDeployment(1, 1, map[string]string{"a": "b"}), false},
+		{"generation only, label change", &Controller{generationOnly: true}, newDeployment(1, 1, nil), newDeployment(1, 1, map[string]string{"a": "b"}), false},
+		{"generation only, spec change", &Controller{generationOnly: true}, newDeployment(1, 1, nil), newDeployment(2, 3, nil), true},
+		{"generation only, untracked", &Controller{generationOnly: true}, newDeployment(0, 1, nil), newDeployment(0, 3, nil), true},
+	}
+
+	for _, tt := range Tests {
+		if got := tt.controller.hasChanged(tt.old, tt.new); got != tt.changed {
+			t.Errorf("%s: expected hasChanged() to be %v, got %v", tt.name, tt.changed, got)
+		}
+	}
+}
+
+func TestNewTweakListOptions(t *testing.T) {
+	tweak := newTweakListOptions(config.ResourceOptions{
+		LabelSelector: "team=payments",
+		FieldSelector: "spec.nodeName=node-1",
+	})
+
+	options := meta_v1.ListOptions{ResourceVersion: "42"}
+	tweak(&options)
+	if options.LabelSelector != "team=payments" || options.FieldSelector != "spec.nodeName=node-1" {
+		t.Fatalf("selectors not applied: %+v", options)
+	}
+	if options.ResourceVersion != "42" {
+		t.Fatalf("unrelated options modified: %+v", options)
+	}
+}
+
+func TestNamespaceFilter(t *testing.T) {
+	var Tests = []struct {
+		conf      config.Config
+		namespace string
+		match     bool
+	}{
+		{config.Config{}, "default", true},
+		{config.Config{Namespaces: []string{"payments", "billing"}}, "billing", true},
+		{config.Config{Namespaces: []string{"payments", "billing"}}, "default", false},
+		{config.Config{Namespaces: []string{"payments"}}, "", true},
+		{config.Config{ExcludeNamespaces: []string{"kube-*", "*-sandbox"}}, "kube-system", false},
+		{config.Config{ExcludeNamespaces: []string{"kube-*", "*-sandbox"}}, "bob-sandbox", false},
+		{config.Config{ExcludeNamespaces: []string{"kube-*", "*-sandbox"}}, "payments", true},
+	}
+
+	for _, tt := range Tests {
+		conf := tt.conf
+		if got := newNamespaceFilter(&conf).matches(tt.namespace); got != tt.match {
+			t.Errorf("%+v: expected matches(%q) to be %v, got %v", tt.conf, tt.namespace, tt.match, got)
+		}
+	}
+}
+
+func TestWatchedNamespace(t *testing.T) {
+	var Tests = []struct {
+		conf      config.Config
+		namespace string
+	}{
+		{config.Config{}, ""},
+		{config.Config{Namespace: "payments"}, "payments"},
+		{config.Config{Namespaces: []string{"billing"}}, "billing"},
+		{config.Config{Namespaces: []string{"payments", "billing"}}, ""},
+	}
+
+	for _, tt := range Tests {
+		conf := tt.conf
+		if got := watchedNamespace(&conf); got != tt.namespace {
+			t.Errorf("%+v: expected %q, got %q", tt.conf, tt.namespace, got)
+		}
+		if !reflect.DeepEqual(conf, tt.conf) {
+			t.Errorf("%+v: configuration modified", tt.conf)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	deployment := newDeployment(1, 1, map[string]string{"app": "foo"})
+	deployment.UID = "1b0c5c8e-7d4a-4bf6-a0e5-2bb8f4f3c2a1"
+	deployment.ResourceVersion = "42"
+
+	var Tests = []struct {
+		includeObjects    bool
+		oldObj, newObj    interface{}
+		hasOld, hasObject bool
+	}{
+		{false, deployment, deployment, false, false},
+		{true, nil, deployment, false, true},
+		{true, deployment, deployment, true, true},
+		{true, deployment, nil, true, false},
+	}
+
+	for _, tt := range Tests {
+		var e event.Event
+		c := &Controller{includeObjects: tt.includeObjects}
+		c.describe(&e, deployment, tt.oldObj, tt.newObj)
+
+		if e.UID != string(deployment.UID) || e.APIVersion != "apps/v1" || e.ResourceVersion != "42" || e.Labels["app"] != "foo" {
+			t.Errorf("unexpected metadata %+v", e)
+		}
+		if (e.OldObject != nil) != tt.hasOld || (e.Object != nil) != tt.hasObject {
+			t.Errorf("%+v: unexpected objects %s and %s", tt, e.OldObject, e.Object)
+		}
+	}
+
+	nodeNotReady := &api_v1.Event{
+		ObjectMeta:     meta_v1.ObjectMeta{Name: "node-1.16a7b3c2d1e0f", Namespace: "default"},
+		InvolvedObject: api_v1.ObjectReference{Kind: "Node", Name: "node-1"},
+	}
+	e := event.Event{Kind: "NodeNotReady", Name: nodeNotReady.Name}
+	(&Controller{}).describe(&e, nodeNotReady, nil, nodeNotReady)
+	if kind, _, name := e.Subject(); kind != "node" || name != "node-1" {
+		t.Errorf("expected the event to be about node node-1, got %s %s", kind, name)
+	}
+}
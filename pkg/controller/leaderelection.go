@@ -0,0 +1,153 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/sirupsen/logrus"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	defaultLeaseName = "kubewatch"
+	namespaceFile    = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// runLeaderElection runs run while holding the lease, until ctx is done.
+// The lease is released once run returned, having saved the checkpoint, so
+// that a standby replica takes over at once from where run stopped.
+func runLeaderElection(ctx context.Context, conf config.LeaderElection, client kubernetes.Interface, run func(ctx context.Context)) {
+	leaseDuration, renewDeadline, retryPeriod, err := conf.Durations()
+	if err != nil {
+		logrus.Fatalf("leaderElection.%v", err)
+	}
+
+	name := conf.LeaseName
+	if name == "" {
+		name = defaultLeaseName
+	}
+	namespace := conf.LeaseNamespace
+	if namespace == "" {
+		namespace = currentNamespace()
+	}
+	identity := leaderIdentity()
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Client: client.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{
+			Identity: identity,
+		},
+	}
+
+	// The election outlives ctx while run stops: it is stopped by ctx only
+	// before the lease is acquired, and by run returning otherwise
+	election, stopElection := context.WithCancel(context.Background())
+	defer stopElection()
+	var (
+		mu      sync.Mutex
+		leading bool
+	)
+	go func() {
+		<-ctx.Done()
+		mu.Lock()
+		defer mu.Unlock()
+		if !leading {
+			stopElection()
+		}
+	}()
+
+	logrus.Infof("Waiting to acquire lease %s/%s as %s", namespace, name, identity)
+	leaderelection.RunOrDie(election, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				defer stopElection()
+				mu.Lock()
+				leading = ctx.Err() == nil
+				mu.Unlock()
+				if !leading {
+					return
+				}
+
+				// run stops when ctx is done, or when the lease is lost
+				runCtx, cancel := context.WithCancel(leaderCtx)
+				defer cancel()
+				go func() {
+					select {
+					case <-ctx.Done():
+					case <-runCtx.Done():
+					}
+					cancel()
+				}()
+				logrus.Infof("Acquired lease %s/%s, starting watchers", namespace, name)
+				run(runCtx)
+			},
+			OnStoppedLeading: func() {
+				if ctx.Err() != nil {
+					logrus.Infof("Released lease %s/%s", namespace, name)
+					return
+				}
+				// Another replica may be sending notifications already: stop at once
+				logrus.Fatalf("Lost lease %s/%s", namespace, name)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					logrus.Infof("Replica %s holds lease %s/%s", leader, namespace, name)
+				}
+			},
+		},
+	})
+}
+
+// currentNamespace returns the namespace kubewatch runs in, or default out of cluster
+func currentNamespace() string {
+	if b, err := ioutil.ReadFile(namespaceFile); err == nil {
+		if ns := strings.TrimSpace(string(b)); ns != "" {
+			return ns
+		}
+	}
+	return meta_v1.NamespaceDefault
+}
+
+// leaderIdentity identifies this replica: its pod name, made unique across restarts
+func leaderIdentity() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "kubewatch"
+	}
+	return hostname + "_" + rand.String(8)
+}
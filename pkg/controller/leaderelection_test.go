@@ -0,0 +1,88 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunLeaderElection(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	conf := config.LeaderElection{
+		Enabled:        true,
+		LeaseNamespace: "kubewatch",
+		LeaseDuration:  "2s",
+		RenewDeadline:  "1s",
+		RetryPeriod:    "100ms",
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	done := make(chan struct{})
+	var stopped bool
+	go func() {
+		defer close(done)
+		runLeaderElection(ctx, conf, client, func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+			// saving the checkpoint, the lease must still be held
+			time.Sleep(200 * time.Millisecond)
+			lease, err := client.CoordinationV1().Leases("kubewatch").Get("kubewatch", meta_v1.GetOptions{})
+			if err != nil || lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" {
+				t.Errorf("expected the lease to be held until run returns, got %v", err)
+			}
+			stopped = true
+		})
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting to acquire the lease")
+	}
+	lease, err := client.CoordinationV1().Leases("kubewatch").Get("kubewatch", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity == "" {
+		t.Fatalf("expected the lease to be held")
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting to release the lease")
+	}
+	if !stopped {
+		t.Errorf("expected runLeaderElection to wait for run to return")
+	}
+	lease, err = client.CoordinationV1().Leases("kubewatch").Get("kubewatch", meta_v1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lease.Spec.HolderIdentity != nil && *lease.Spec.HolderIdentity != "" {
+		t.Errorf("expected the lease to be released, held by %s", *lease.Spec.HolderIdentity)
+	}
+}
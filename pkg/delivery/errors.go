@@ -0,0 +1,142 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package delivery tells apart the ways handlers can fail to deliver an event.
+
+Failures are transient unless stated otherwise: network errors, 5xx and 429
+responses are worth retrying, possibly after a delay requested by the
+service, while a rejected message or a bad configuration is permanent.
+
+Spool keeps the events waiting to be delivered on disk, along with the
+ones that could not be delivered.
+*/
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkmik/multierror"
+)
+
+// Error is a failed delivery.
+type Error struct {
+	Err error
+	// Permanent failures are not retried.
+	Permanent bool
+	// RetryAfter is the delay requested by the service before trying again.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks err as a failure that retrying will not fix.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Err: err, Permanent: true}
+}
+
+// RetryAfter marks err as a transient failure to retry no sooner than after d.
+func RetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Err: err, RetryAfter: d}
+}
+
+// IsPermanent reports whether err, or every error it aggregates, is permanent.
+func IsPermanent(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, err := range multierror.Split(err) {
+		var e *Error
+		if !errors.As(err, &e) || !e.Permanent {
+			return false
+		}
+	}
+	return true
+}
+
+// Delay returns the longest delay requested before retrying err.
+func Delay(err error) time.Duration {
+	var d time.Duration
+	if err == nil {
+		return d
+	}
+	for _, err := range multierror.Split(err) {
+		var e *Error
+		if errors.As(err, &e) && e.RetryAfter > d {
+			d = e.RetryAfter
+		}
+	}
+	return d
+}
+
+// CheckResponse returns an error for an HTTP response without a 2xx status:
+// 408, 429 and 5xx are transient, honouring Retry-After, other statuses are permanent.
+// The caller remains responsible for closing the response body.
+func CheckResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	err := fmt.Errorf("unexpected response %s", resp.Status)
+	if body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512)); len(body) > 0 {
+		err = fmt.Errorf("unexpected response %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode >= 500:
+		return RetryAfter(err, ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()))
+	}
+	return Permanent(err)
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, either a number of
+// seconds or an HTTP date. It returns 0 for an empty or invalid value.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mkmik/multierror"
+)
+
+func TestCheckResponse(t *testing.T) {
+	var Tests = []struct {
+		status     int
+		retryAfter string
+		isErr      bool
+		permanent  bool
+		delay      time.Duration
+	}{
+		{http.StatusOK, "", false, false, 0},
+		{http.StatusNoContent, "", false, false, 0},
+		{http.StatusBadRequest, "", true, true, 0},
+		{http.StatusNotFound, "", true, true, 0},
+		{http.StatusTooManyRequests, "30", true, false, 30 * time.Second},
+		{http.StatusServiceUnavailable, "", true, false, 0},
+		{http.StatusBadGateway, "2", true, false, 2 * time.Second},
+	}
+
+	for _, tt := range Tests {
+		rec := httptest.NewRecorder()
+		if tt.retryAfter != "" {
+			rec.Header().Set("Retry-After", tt.retryAfter)
+		}
+		rec.WriteHeader(tt.status)
+		fmt.Fprint(rec, "nope")
+
+		err := CheckResponse(rec.Result())
+		if (err != nil) != tt.isErr {
+			t.Fatalf("%d: unexpected error %v", tt.status, err)
+		}
+		if got := IsPermanent(err); got != tt.permanent {
+			t.Errorf("%d: expected IsPermanent() to be %v, got %v", tt.status, tt.permanent, got)
+		}
+		if got := Delay(err); got != tt.delay {
+			t.Errorf("%d: expected a delay of %s, got %s", tt.status, tt.delay, got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var Tests = []struct {
+		value string
+		delay time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"-1", 0},
+		{"Wed, 01 Jan 2020 12:01:00 GMT", time.Minute},
+		{"Wed, 01 Jan 2020 11:00:00 GMT", 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range Tests {
+		if got := ParseRetryAfter(tt.value, now); got != tt.delay {
+			t.Errorf("ParseRetryAfter(%q): expected %s, got %s", tt.value, tt.delay, got)
+		}
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+	permanent := Permanent(fmt.Errorf("rejected"))
+	transient := fmt.Errorf("connection refused")
+
+	var Tests = []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{"nil", nil, false},
+		{"plain error", transient, false},
+		{"permanent", permanent, true},
+		{"wrapped permanent", fmt.Errorf("slack: %w", permanent), true},
+		{"all permanent", multierror.Join([]error{permanent, permanent}), true},
+		{"some transient", multierror.Join([]error{permanent, transient}), false},
+	}
+
+	for _, tt := range Tests {
+		if got := IsPermanent(tt.err); got != tt.permanent {
+			t.Errorf("%s: expected IsPermanent() to be %v, got %v", tt.name, tt.permanent, got)
+		}
+	}
+}
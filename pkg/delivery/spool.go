@@ -0,0 +1,220 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+const (
+	pendingDir = "pending"
+	deadDir    = "dead"
+)
+
+// Record is an event stored on disk for a handler.
+type Record struct {
+	ID      string      `json:"id"`
+	Handler string      `json:"handler"`
+	Event   event.Event `json:"event"`
+	Queued  time.Time   `json:"queued"`
+	// Error is the last delivery error of a dead-lettered event.
+	Error  string    `json:"error,omitempty"`
+	Failed time.Time `json:"failed,omitempty"`
+}
+
+// Spool is a durable delivery queue: each event waiting to be delivered is
+// stored as a file under pending/<handler>, and the events that could not be
+// delivered are moved under dead/<handler>, the dead-letter store.
+//
+// Files are written atomically, so that several processes, e.g. kubewatch
+// and the kubewatch dlq command, can use the same directory.
+type Spool struct {
+	dir string
+
+	mu   sync.Mutex
+	last string
+	seq  int
+}
+
+// OpenSpool opens the spool in dir, creating it if needed.
+func OpenSpool(dir string) (*Spool, error) {
+	for _, d := range []string{pendingDir, deadDir} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0700); err != nil {
+			return nil, err
+		}
+	}
+	return &Spool{dir: dir}, nil
+}
+
+// Add stores an event to deliver to a handler.
+func (s *Spool) Add(handler string, e event.Event) (*Record, error) {
+	r := &Record{ID: s.nextID(), Handler: handler, Event: e, Queued: time.Now()}
+	if err := s.write(pendingDir, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Pending returns the events still to deliver to a handler, oldest first.
+func (s *Spool) Pending(handler string) ([]*Record, error) {
+	return s.read(pendingDir, handler)
+}
+
+// Done removes a delivered event.
+func (s *Spool) Done(r *Record) error {
+	return s.remove(pendingDir, r)
+}
+
+// Bury moves an event that could not be delivered to the dead-letter store.
+func (s *Spool) Bury(r *Record, reason error) error {
+	r.Error = reason.Error()
+	r.Failed = time.Now()
+	if err := s.write(deadDir, r); err != nil {
+		return err
+	}
+	return s.remove(pendingDir, r)
+}
+
+// Dead returns the dead-lettered events of a handler, or of all handlers
+// when handler is empty, oldest first.
+func (s *Spool) Dead(handler string) ([]*Record, error) {
+	if handler != "" {
+		return s.read(deadDir, handler)
+	}
+
+	dirs, err := ioutil.ReadDir(filepath.Join(s.dir, deadDir))
+	if err != nil {
+		return nil, err
+	}
+	var records []*Record
+	for _, d := range dirs {
+		name, err := url.PathUnescape(d.Name())
+		if !d.IsDir() || err != nil {
+			continue
+		}
+		r, err := s.read(deadDir, name)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r...)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records, nil
+}
+
+// Remove deletes a dead-lettered event.
+func (s *Spool) Remove(r *Record) error {
+	return s.remove(deadDir, r)
+}
+
+// nextID returns identifiers sorting in creation order.
+func (s *Spool) nextID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := time.Now().UTC().Format("20060102T150405.000000000Z")
+	if id == s.last {
+		s.seq++
+	} else {
+		s.last, s.seq = id, 0
+	}
+	return fmt.Sprintf("%s-%03d", id, s.seq)
+}
+
+func (s *Spool) handlerDir(state, handler string) string {
+	return filepath.Join(s.dir, state, url.PathEscape(handler))
+}
+
+func (s *Spool) write(state string, r *Record) error {
+	dir := s.handlerDir(state, r.Handler)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, r.ID+".json"))
+}
+
+func (s *Spool) read(state, handler string) ([]*Record, error) {
+	dir := s.handlerDir(state, handler)
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*Record
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".json") {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if os.IsNotExist(err) {
+			// delivered or removed meanwhile
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var r Record
+		if err := json.Unmarshal(b, &r); err != nil {
+			return nil, fmt.Errorf("%s: %v", filepath.Join(dir, f.Name()), err)
+		}
+		records = append(records, &r)
+	}
+	// ReadDir sorts by file name, i.e. by ID
+	return records, nil
+}
+
+func (s *Spool) remove(state string, r *Record) error {
+	err := os.Remove(filepath.Join(s.handlerDir(state, r.Handler), r.ID+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
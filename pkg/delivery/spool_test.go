@@ -0,0 +1,112 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delivery
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func newTestSpool(t *testing.T) (*Spool, func()) {
+	dir, err := ioutil.TempDir("", "kubewatch-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, func() { os.RemoveAll(dir) }
+}
+
+func TestSpool(t *testing.T) {
+	s, cleanup := newTestSpool(t)
+	defer cleanup()
+
+	var records []*Record
+	for i := 0; i < 3; i++ {
+		e := event.Event{Name: fmt.Sprintf("foo-%d", i), Kind: "pod", Reason: "Created", Labels: map[string]string{"app": "foo"}}
+		r, err := s.Add("sre/slack", e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	if _, err := s.Add("webhook", event.Event{Name: "bar"}); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := s.Pending("sre/slack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 3 {
+		t.Fatalf("expected 3 pending events, got %d", len(pending))
+	}
+	for i, r := range pending {
+		if r.ID != records[i].ID || !reflect.DeepEqual(r.Event, records[i].Event) {
+			t.Errorf("pending[%d]: expected %+v, got %+v", i, records[i], r)
+		}
+	}
+
+	if err := s.Done(pending[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Bury(pending[1], fmt.Errorf("channel_not_found")); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ = s.Pending("sre/slack"); len(pending) != 1 || pending[0].ID != records[2].ID {
+		t.Errorf("expected only %s to be left pending, got %+v", records[2].ID, pending)
+	}
+
+	dead, err := s.Dead("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID != records[1].ID || dead[0].Handler != "sre/slack" || dead[0].Error != "channel_not_found" {
+		t.Fatalf("unexpected dead-lettered events %+v", dead)
+	}
+	if dead, _ = s.Dead("webhook"); len(dead) != 0 {
+		t.Errorf("expected no dead-lettered events for webhook, got %+v", dead)
+	}
+
+	if err := s.Remove(records[1]); err != nil {
+		t.Fatal(err)
+	}
+	if dead, _ = s.Dead("sre/slack"); len(dead) != 0 {
+		t.Errorf("expected the dead-lettered event to be removed, got %+v", dead)
+	}
+}
+
+func TestSpoolIDsSortInOrder(t *testing.T) {
+	s, cleanup := newTestSpool(t)
+	defer cleanup()
+
+	last := ""
+	for i := 0; i < 100; i++ {
+		id := s.nextID()
+		if id <= last {
+			t.Fatalf("expected %q to sort after %q", id, last)
+		}
+		last = id
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DefaultIgnoredPaths lists the fields that change without any user action
+// and are therefore left out of update diffs.
+var DefaultIgnoredPaths = []string{
+	"status",
+	"metadata.resourceVersion",
+	"metadata.managedFields",
+	"metadata.generation",
+	`metadata.annotations["kubectl.kubernetes.io/last-applied-configuration"]`,
+}
+
+// Change describes a field that differs between the old and new version of an updated object
+type Change struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// OldValue returns the old value of the field in compact JSON form.
+func (c Change) OldValue() string {
+	return formatValue(c.Old)
+}
+
+// NewValue returns the new value of the field in compact JSON form.
+func (c Change) NewValue() string {
+	return formatValue(c.New)
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.OldValue(), c.NewValue())
+}
+
+func formatValue(v interface{}) string {
+	if v == nil {
+		return "<none>"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// Diff returns the fields that differ between two versions of a k8s object,
+// skipping DefaultIgnoredPaths and the given extra paths (and everything below them).
+func Diff(oldObj, newObj interface{}, ignoredPaths ...string) ([]Change, error) {
+	oldMap, err := toMap(oldObj)
+	if err != nil {
+		return nil, err
+	}
+	newMap, err := toMap(newObj)
+	if err != nil {
+		return nil, err
+	}
+
+	d := differ{ignored: append(append([]string{}, DefaultIgnoredPaths...), ignoredPaths...)}
+	d.diff("", oldMap, newMap)
+	return d.changes, nil
+}
+
+// Fingerprint returns a hash of a k8s object that changes exactly when Diff
+// would report changes, i.e. ignoring the same paths.
+func Fingerprint(obj interface{}, ignoredPaths ...string) (string, error) {
+	m, err := toMap(obj)
+	if err != nil {
+		return "", err
+	}
+
+	d := differ{ignored: append(append([]string{}, DefaultIgnoredPaths...), ignoredPaths...)}
+	b, err := json.Marshal(d.prune("", m))
+	if err != nil {
+		return "", err
+	}
+	h := fnv.New64a()
+	h.Write(b)
+	return fmt.Sprintf("%016x", h.Sum64()), nil
+}
+
+func toMap(obj interface{}) (map[string]interface{}, error) {
+	if u, ok := obj.(runtime.Unstructured); ok {
+		return u.UnstructuredContent(), nil
+	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
+
+type differ struct {
+	ignored []string
+	changes []Change
+}
+
+func (d *differ) isIgnored(path string) bool {
+	for _, p := range d.ignored {
+		if path == p || strings.HasPrefix(path, p+".") || strings.HasPrefix(path, p+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *differ) diff(path string, a, b interface{}) {
+	if d.isIgnored(path) {
+		return
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			for _, k := range unionKeys(av, bv) {
+				d.diff(fieldPath(path, k), av[k], bv[k])
+			}
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok && len(av) == len(bv) {
+			for i := range av {
+				d.diff(fmt.Sprintf("%s[%d]", path, i), av[i], bv[i])
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		d.changes = append(d.changes, Change{Path: path, Old: a, New: b})
+	}
+}
+
+// prune returns a copy of v without the ignored paths
+func (d *differ) prune(path string, v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(vv))
+		for k, e := range vv {
+			if p := fieldPath(path, k); !d.isIgnored(p) {
+				m[k] = d.prune(p, e)
+			}
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(vv))
+		for i, e := range vv {
+			s[i] = d.prune(fmt.Sprintf("%s[%d]", path, i), e)
+		}
+		return s
+	}
+	return v
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// fieldPath appends a key to a JSON path, quoting keys such as
+// "app.kubernetes.io/name" that would otherwise be ambiguous.
+func fieldPath(path, key string) string {
+	if strings.ContainsAny(key, `.[]"`) {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"reflect"
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func deployment(image string, replicas int32, labels map[string]string) *apps_v1.Deployment {
+	return &apps_v1.Deployment{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:            "foo",
+			Namespace:       "new",
+			Labels:          labels,
+			ResourceVersion: image,
+		},
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: &replicas,
+			Template: api_v1.PodTemplateSpec{
+				Spec: api_v1.PodSpec{
+					Containers: []api_v1.Container{{Name: "app", Image: image}},
+				},
+			},
+		},
+		Status: apps_v1.DeploymentStatus{ObservedGeneration: int64(replicas)},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oldObj := deployment("nginx:1.18", 1, map[string]string{"app.kubernetes.io/name": "foo"})
+	newObj := deployment("nginx:1.19", 3, map[string]string{"app.kubernetes.io/name": "bar"})
+
+	changes, err := Diff(oldObj, newObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Change{
+		{Path: `metadata.labels["app.kubernetes.io/name"]`, Old: "foo", New: "bar"},
+		{Path: "spec.replicas", Old: int64(1), New: int64(3)},
+		{Path: "spec.template.spec.containers[0].image", Old: "nginx:1.18", New: "nginx:1.19"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+}
+
+func TestDiffIgnoredPaths(t *testing.T) {
+	oldObj := deployment("nginx:1.18", 1, nil)
+	newObj := deployment("nginx:1.18", 1, map[string]string{"team": "payments"})
+	newObj.Status.ObservedGeneration = 42
+
+	changes, err := Diff(oldObj, newObj, "metadata.labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+}
+
+func TestDiffUnstructured(t *testing.T) {
+	oldObj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"dnsNames": []interface{}{"a.example.com"}},
+	}}
+	newObj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"dnsNames": []interface{}{"a.example.com", "b.example.com"}},
+	}}
+
+	changes, err := Diff(oldObj, newObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "spec.dnsNames" {
+		t.Fatalf("expected a single change of spec.dnsNames, got %v", changes)
+	}
+	if got, want := changes[0].String(), `spec.dnsNames: ["a.example.com"] -> ["a.example.com","b.example.com"]`; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	statusOnly := deployment("nginx:1.18", 1, nil)
+	statusOnly.ResourceVersion = "42"
+	statusOnly.Status.ObservedGeneration = 42
+
+	var Tests = []struct {
+		name    string
+		obj     *apps_v1.Deployment
+		ignored []string
+		same    bool
+	}{
+		{"status churn", statusOnly, nil, true},
+		{"image change", deployment("nginx:1.19", 1, nil), nil, false},
+		{"label change", deployment("nginx:1.18", 1, map[string]string{"team": "payments"}), nil, false},
+		{"ignored label change", deployment("nginx:1.18", 1, map[string]string{"team": "payments"}), []string{"metadata.labels"}, true},
+	}
+
+	for _, tt := range Tests {
+		before, err := Fingerprint(deployment("nginx:1.18", 1, nil), tt.ignored...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after, err := Fingerprint(tt.obj, tt.ignored...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (before == after) != tt.same {
+			t.Errorf("%s: expected same fingerprint to be %v, got %s and %s", tt.name, tt.same, before, after)
+		}
+	}
+}
@@ -14,7 +14,10 @@ limitations under the License.
 package event
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/bitnami-labs/kubewatch/pkg/utils"
 	apps_v1 "k8s.io/api/apps/v1"
@@ -22,6 +25,8 @@ import (
 	api_v1 "k8s.io/api/core/v1"
 	ext_v1beta1 "k8s.io/api/extensions/v1beta1"
 	rbac_v1beta1 "k8s.io/api/rbac/v1beta1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 // Event represent an event got from k8s api server
@@ -35,6 +40,26 @@ type Event struct {
 	Reason    string
 	Status    string
 	Name      string
+	// Identity and version of the object: its API group/version, e.g. apps/v1.
+	UID             string
+	APIVersion      string
+	ResourceVersion string
+	// Labels and annotations of the object.
+	Labels      map[string]string
+	Annotations map[string]string
+	// Owners of the object, e.g. the ReplicaSet of a Pod.
+	OwnerReferences []meta_v1.OwnerReference
+	// Time at which the change was observed.
+	Timestamp time.Time
+	// Diff holds the fields changed by an update, if any.
+	Diff []Change
+	// Old and new versions of the object as JSON, if enabled with includeObjects:
+	// creates only carry the new version, deletes only the old one.
+	OldObject json.RawMessage `json:",omitempty"`
+	Object    json.RawMessage `json:",omitempty"`
+	// Object the k8s Event behind the NodeReady, NodeNotReady, NodeRebooted
+	// and Backoff kinds reports on.
+	InvolvedObject *api_v1.ObjectReference `json:",omitempty"`
 }
 
 var m = map[string]string{
@@ -86,6 +111,8 @@ func New(obj interface{}, action string) Event {
 		kind = "cluster role"
 	case *api_v1.ServiceAccount:
 		kind = "service account"
+	case *unstructured.Unstructured:
+		kind = strings.ToLower(object.GetKind())
 	case Event:
 		name = object.Name
 		kind = object.Kind
@@ -93,17 +120,84 @@ func New(obj interface{}, action string) Event {
 	}
 
 	kbEvent := Event{
-		Namespace: namespace,
-		Kind:      kind,
-		Component: component,
-		Host:      host,
-		Reason:    reason,
-		Status:    status,
-		Name:      name,
+		Namespace:       namespace,
+		Kind:            kind,
+		Component:       component,
+		Host:            host,
+		Reason:          reason,
+		Status:          status,
+		Name:            name,
+		UID:             string(objectMeta.UID),
+		APIVersion:      utils.GetObjectGroupVersionKind(obj).GroupVersion().String(),
+		ResourceVersion: objectMeta.ResourceVersion,
+		Labels:          objectMeta.Labels,
+		Annotations:     objectMeta.Annotations,
+		OwnerReferences: objectMeta.OwnerReferences,
+		Timestamp:       time.Now(),
 	}
 	return kbEvent
 }
 
+// ObjectJSON returns a k8s object as JSON, including its apiVersion and kind,
+// or nil for a nil object.
+func ObjectJSON(obj interface{}) (json.RawMessage, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	m, err := toMap(obj)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := m["kind"]; !ok {
+		gvk := utils.GetObjectGroupVersionKind(obj)
+		if gvk.Kind != "" {
+			m["apiVersion"], m["kind"] = gvk.GroupVersion().String(), gvk.Kind
+		}
+	}
+	return json.Marshal(m)
+}
+
+// Subject returns the kind, namespace and name of the object the event is
+// about: the node or pod a k8s Event reports on, if any, else the object itself.
+// A condition and its recovery, e.g. NodeNotReady and NodeReady, share the same subject.
+func (e *Event) Subject() (kind, namespace, name string) {
+	if o := e.InvolvedObject; o != nil {
+		return strings.ToLower(o.Kind), o.Namespace, o.Name
+	}
+	return e.Kind, e.Namespace, e.Name
+}
+
+// recoveries maps the kinds of the events reporting that a condition cleared
+// to the kinds of the events reporting the condition.
+// Pods in CrashLoopBackOff (Backoff) have no such event: Kubernetes reports
+// no clearing event once a container keeps running, so their alerts are
+// not resolved by kubewatch.
+var recoveries = map[string]string{
+	"NodeReady": "NodeNotReady",
+}
+
+// Recovered reports whether the event reports that a condition of its subject
+// cleared, e.g. NodeReady after NodeNotReady.
+func (e *Event) Recovered() bool {
+	return e.Recovers() != ""
+}
+
+// Recovers returns the kind of the events reporting the condition whose
+// recovery the event reports, e.g. NodeNotReady for NodeReady, or "" if none.
+func (e *Event) Recovers() string {
+	if e.Expired() {
+		return ""
+	}
+	return recoveries[e.Kind]
+}
+
+// Expired reports whether the event reports the deletion of a k8s Event,
+// which Kubernetes deletes once expired, an hour after it by default: neither
+// the condition the k8s Event reported, nor its recovery.
+func (e *Event) Expired() bool {
+	return e.InvolvedObject != nil && e.Reason == "Deleted"
+}
+
 // Message returns event message in standard format.
 // included as a part of event packege to enhance code resuablity across handlers.
 func (e *Event) Message() (msg string) {
@@ -160,3 +254,12 @@ func (e *Event) Message() (msg string) {
 	}
 	return msg
 }
+
+// DiffMessage returns the fields changed by an update, one per line.
+func (e *Event) DiffMessage() string {
+	lines := make([]string, 0, len(e.Diff))
+	for _, c := range e.Diff {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "\n")
+}
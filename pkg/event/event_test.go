@@ -0,0 +1,131 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package event
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newPod() *api_v1.Pod {
+	return &api_v1.Pod{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:            "foo-6d4cf56db6-x2kq8",
+			Namespace:       "default",
+			UID:             "1b0c5c8e-7d4a-4bf6-a0e5-2bb8f4f3c2a1",
+			ResourceVersion: "42",
+			Labels:          map[string]string{"app": "foo"},
+			OwnerReferences: []meta_v1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "foo-6d4cf56db6"}},
+		},
+		Spec: api_v1.PodSpec{NodeName: "node-1"},
+	}
+}
+
+func TestNew(t *testing.T) {
+	pod := newPod()
+	e := New(pod, "created")
+
+	if e.Kind != "pod" || e.Host != "node-1" || e.Status != "Normal" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if e.UID != string(pod.UID) || e.APIVersion != "v1" || e.ResourceVersion != "42" {
+		t.Errorf("unexpected identity %s %s %s", e.UID, e.APIVersion, e.ResourceVersion)
+	}
+	if !reflect.DeepEqual(e.OwnerReferences, pod.OwnerReferences) {
+		t.Errorf("expected owners %v, got %v", pod.OwnerReferences, e.OwnerReferences)
+	}
+	if e.Timestamp.IsZero() {
+		t.Errorf("expected the event to be timestamped")
+	}
+}
+
+func TestObjectJSON(t *testing.T) {
+	b, err := ObjectJSON(newPod())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var obj struct {
+		APIVersion string `json:"apiVersion"`
+		Kind       string `json:"kind"`
+		Metadata   struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj.APIVersion != "v1" || obj.Kind != "Pod" || obj.Metadata.Name != "foo-6d4cf56db6-x2kq8" {
+		t.Errorf("unexpected object %s", b)
+	}
+
+	if b, err := ObjectJSON(nil); b != nil || err != nil {
+		t.Errorf("expected no JSON for a nil object, got %s, %v", b, err)
+	}
+}
+
+func TestSubject(t *testing.T) {
+	var Tests = []struct {
+		e                     Event
+		kind, namespace, name string
+	}{
+		{Event{Kind: "pod", Namespace: "default", Name: "foo"}, "pod", "default", "foo"},
+		{
+			Event{Kind: "NodeNotReady", Namespace: "default", Name: "node-1.16a7b3c2d1e0f",
+				InvolvedObject: &api_v1.ObjectReference{Kind: "Node", Name: "node-1"}},
+			"node", "", "node-1",
+		},
+	}
+
+	for _, tt := range Tests {
+		if kind, namespace, name := tt.e.Subject(); kind != tt.kind || namespace != tt.namespace || name != tt.name {
+			t.Errorf("expected %s %s/%s, got %s %s/%s", tt.kind, tt.namespace, tt.name, kind, namespace, name)
+		}
+	}
+}
+
+func TestRecovers(t *testing.T) {
+	var Tests = []struct {
+		kind, recovers string
+	}{
+		{"NodeReady", "NodeNotReady"},
+		{"NodeNotReady", ""},
+		// CrashLoopBackOff is not resolved
+		{"Backoff", ""},
+		{"pod", ""},
+	}
+
+	for _, tt := range Tests {
+		e := Event{Kind: tt.kind}
+		if got := e.Recovers(); got != tt.recovers {
+			t.Errorf("%s: expected Recovers() to be %q, got %q", tt.kind, tt.recovers, got)
+		}
+		if e.Recovered() != (tt.recovers != "") {
+			t.Errorf("%s: expected Recovered() to be %v", tt.kind, tt.recovers != "")
+		}
+	}
+
+	// the expiry of a NodeReady k8s Event is no recovery
+	node := api_v1.ObjectReference{Kind: "Node", Name: "node-1"}
+	expired := Event{Kind: "NodeReady", Reason: "Deleted", Status: "Danger", InvolvedObject: &node}
+	if !expired.Expired() || expired.Recovered() {
+		t.Errorf("expected an expired event not to be a recovery")
+	}
+	if deleted := (Event{Kind: "node", Reason: "Deleted"}); deleted.Expired() {
+		t.Errorf("expected the deletion of an object not to be an expiry")
+	}
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package filter decides which events are reported, using CEL expressions
+(https://github.com/google/cel-spec) evaluated against the event and the
+object it is about.
+
+Expressions see two variables:
+
+	event   the kubewatch event: event.namespace, event.kind, event.name,
+	        event.uid, event.reason, event.status, event.component,
+	        event.host, event.labels, event.annotations
+	object  the Kubernetes object as found in the API, e.g.
+	        object.metadata.labels, object.spec.replicas
+
+For example:
+
+	object.metadata.labels.env == "prod" && event.reason == "Deleted"
+*/
+package filter
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	"github.com/sirupsen/logrus"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// Rule is a single filtering expression.
+type Rule struct {
+	// Expression is a CEL expression evaluating to a bool.
+	Expression string
+	// Exclude drops the matching events instead of reporting only them.
+	Exclude bool
+}
+
+// Filter holds compiled rules.
+// An event is reported when it matches at least one include rule (or there are none)
+// and no exclude rule.
+type Filter struct {
+	include []cel.Program
+	exclude []cel.Program
+}
+
+var env *cel.Env
+
+func init() {
+	var err error
+	env, err = cel.NewEnv(cel.Declarations(
+		decls.NewVar("event", decls.NewMapType(decls.String, decls.Dyn)),
+		decls.NewVar("object", decls.NewMapType(decls.String, decls.Dyn)),
+	))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Compile checks that an expression is valid and evaluates to a bool.
+func Compile(expression string) (cel.Program, error) {
+	ast, iss := env.Compile(expression)
+	if iss.Err() != nil {
+		return nil, iss.Err()
+	}
+	if t := ast.ResultType(); !isBoolOrDyn(t) {
+		return nil, fmt.Errorf("expression must evaluate to a bool, not %s", cel.FormatType(t))
+	}
+	return env.Program(ast)
+}
+
+func isBoolOrDyn(t *exprpb.Type) bool {
+	return t.GetPrimitive() == exprpb.Type_BOOL || t.GetDyn() != nil
+}
+
+// New compiles the given rules.
+func New(rules []Rule) (*Filter, error) {
+	f := &Filter{}
+	for _, r := range rules {
+		prg, err := Compile(r.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("filter %q: %v", r.Expression, err)
+		}
+		if r.Exclude {
+			f.exclude = append(f.exclude, prg)
+		} else {
+			f.include = append(f.include, prg)
+		}
+	}
+	return f, nil
+}
+
+// Matches reports whether an event about obj must be reported.
+// Expressions that fail to evaluate, e.g. because of a missing field, do not match.
+func (f *Filter) Matches(e event.Event, obj interface{}) bool {
+	if f == nil || (len(f.include) == 0 && len(f.exclude) == 0) {
+		return true
+	}
+
+	vars := map[string]interface{}{
+		"event":  eventVars(e),
+		"object": objectVars(obj),
+	}
+
+	if len(f.include) > 0 && !anyTrue(f.include, vars) {
+		return false
+	}
+	return !anyTrue(f.exclude, vars)
+}
+
+func anyTrue(programs []cel.Program, vars map[string]interface{}) bool {
+	for _, prg := range programs {
+		out, _, err := prg.Eval(vars)
+		if err != nil {
+			logrus.Debugf("filter evaluation failed: %v", err)
+			continue
+		}
+		if out == types.True {
+			return true
+		}
+	}
+	return false
+}
+
+func eventVars(e event.Event) map[string]interface{} {
+	return map[string]interface{}{
+		"namespace":   e.Namespace,
+		"kind":        e.Kind,
+		"name":        e.Name,
+		"uid":         e.UID,
+		"reason":      e.Reason,
+		"status":      e.Status,
+		"component":   e.Component,
+		"host":        e.Host,
+		"labels":      stringMap(e.Labels),
+		"annotations": stringMap(e.Annotations),
+	}
+}
+
+// stringMap returns m, or an empty map so that expressions like
+// has(event.labels.env) evaluate on events without labels
+func stringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
+
+func objectVars(obj interface{}) map[string]interface{} {
+	switch o := obj.(type) {
+	case nil:
+		return map[string]interface{}{}
+	case runtime.Unstructured:
+		return o.UnstructuredContent()
+	}
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		logrus.Debugf("cannot convert %T for filtering: %v", obj, err)
+		return map[string]interface{}{}
+	}
+	return m
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"testing"
+
+	api_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestCompile(t *testing.T) {
+	var Tests = []struct {
+		expression string
+		isErr      bool
+	}{
+		{`event.reason == "Deleted"`, false},
+		{`object.metadata.labels.env == "prod"`, false},
+		{`has(object.metadata.labels.env)`, false},
+		{`object.spec.paused`, false},
+		{`event.reason ==`, true},
+		{`"Deleted"`, true},
+		{`unknown.reason == "Deleted"`, true},
+	}
+
+	for _, tt := range Tests {
+		if _, err := Compile(tt.expression); (err != nil) != tt.isErr {
+			t.Fatalf("Compile(%q): unexpected error %v", tt.expression, err)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	prodPod := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{
+		Name:   "foo",
+		Labels: map[string]string{"env": "prod"},
+	}}
+	devPod := &api_v1.Pod{ObjectMeta: meta_v1.ObjectMeta{Name: "foo"}}
+	deleted := event.Event{Name: "foo", Kind: "pod", Reason: "Deleted"}
+	created := event.Event{Name: "foo", Kind: "pod", Reason: "Created"}
+	prodDeleted := event.Event{
+		Name: "foo", Kind: "pod", Reason: "Deleted", UID: "0c6f2a4e",
+		Labels:      map[string]string{"env": "prod"},
+		Annotations: map[string]string{"owner": "payments"},
+	}
+
+	prodDeletes := Rule{Expression: `object.metadata.labels.env == "prod" && event.reason == "Deleted"`}
+	noCreates := Rule{Expression: `event.reason == "Created"`, Exclude: true}
+	prodLabel := Rule{Expression: `event.labels.env == "prod"`}
+	owned := Rule{Expression: `has(event.annotations.owner) && event.uid != ""`}
+
+	var Tests = []struct {
+		name  string
+		rules []Rule
+		e     event.Event
+		obj   interface{}
+		match bool
+	}{
+		{"no rules", nil, created, devPod, true},
+		{"include match", []Rule{prodDeletes}, deleted, prodPod, true},
+		{"include no match", []Rule{prodDeletes}, created, prodPod, false},
+		{"include missing label", []Rule{prodDeletes}, deleted, devPod, false},
+		{"exclude match", []Rule{noCreates}, created, prodPod, false},
+		{"exclude no match", []Rule{noCreates}, deleted, prodPod, true},
+		{"include and exclude", []Rule{prodDeletes, noCreates}, deleted, prodPod, true},
+		{"no object", []Rule{prodDeletes}, deleted, nil, false},
+		{"event labels", []Rule{prodLabel}, prodDeleted, nil, true},
+		{"event without labels", []Rule{prodLabel}, deleted, nil, false},
+		{"event annotations and uid", []Rule{owned}, prodDeleted, nil, true},
+		{"event without annotations", []Rule{owned}, deleted, nil, false},
+	}
+
+	for _, tt := range Tests {
+		f, err := New(tt.rules)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := f.Matches(tt.e, tt.obj); got != tt.match {
+			t.Errorf("%s: expected Matches() to be %v, got %v", tt.name, tt.match, got)
+		}
+	}
+}
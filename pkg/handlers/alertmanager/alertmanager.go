@@ -0,0 +1,205 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alertmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/sirupsen/logrus"
+)
+
+var alertmanagerErrMsg = `
+%s
+
+You need to set the Alertmanager url
+using "--url/-u" or using environment variables:
+
+export KW_ALERTMANAGER_URL=alertmanager_url
+
+Command line flags will override environment variables
+
+`
+
+// severities maps the status of events to the severity label of alerts.
+var severities = map[string]string{
+	"Normal":  "info",
+	"Warning": "warning",
+	"Danger":  "critical",
+}
+
+// Alertmanager handler implements handler.Handler interface,
+// Notify event to Alertmanager as alerts, resolving them on recovery
+type Alertmanager struct {
+	Url      string
+	Username string
+	Password string
+	Cluster  string
+
+	renderer *message.Renderer
+}
+
+// Alert is an alert posted to the Alertmanager API v2.
+type Alert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      *time.Time        `json:"endsAt,omitempty"`
+}
+
+// Init prepares Alertmanager configuration
+func (a *Alertmanager) Init(c *config.Config) error {
+	url := c.Handler.Alertmanager.Url
+
+	if url == "" {
+		url = os.Getenv("KW_ALERTMANAGER_URL")
+	}
+
+	a.Url = strings.TrimSuffix(url, "/")
+	a.Username = c.Handler.Alertmanager.Username
+	a.Password = c.Handler.Alertmanager.Password
+	a.Cluster = c.ClusterName
+
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	a.renderer = renderer
+
+	return checkMissingAlertmanagerVars(a)
+}
+
+// Handle handles an event.
+func (a *Alertmanager) Handle(e event.Event) error {
+	if e.Expired() {
+		logrus.Debugf("alertmanager: skipping %s event for %s, expired", e.Reason, e.Name)
+		return nil
+	}
+	alert := prepareAlert(e, a)
+
+	err := a.postAlerts([]*Alert{alert})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Alert %s successfully sent to %s at %s ", alert.Labels["alertname"], a.Url, time.Now())
+	return nil
+}
+
+func checkMissingAlertmanagerVars(a *Alertmanager) error {
+	if a.Url == "" {
+		return fmt.Errorf(alertmanagerErrMsg, "Missing Alertmanager url")
+	}
+
+	return nil
+}
+
+// alertName names the alerts of an event: the kind of the events reporting a
+// condition, e.g. NodeNotReady, or the kind and reason of an object change,
+// e.g. DeploymentUpdated.
+func alertName(e event.Event) string {
+	if e.InvolvedObject != nil {
+		return e.Kind
+	}
+	var name strings.Builder
+	for _, word := range strings.Fields(e.Kind + " " + e.Reason) {
+		r, size := utf8.DecodeRuneInString(word)
+		name.WriteRune(unicode.ToUpper(r))
+		name.WriteString(word[size:])
+	}
+	return name.String()
+}
+
+// prepareAlert returns the alert of an event. The recovery of a condition
+// resolves the alert of the condition, which bears the same labels: conditions
+// are critical whatever the status of their events, e.g. Warning once the
+// k8s Event reporting them is updated.
+func prepareAlert(e event.Event, a *Alertmanager) *Alert {
+	timestamp := e.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	alert := &Alert{
+		Annotations: map[string]string{"message": a.renderer.Message(e)},
+		StartsAt:    timestamp,
+	}
+
+	labelled := e
+	if condition := e.Recovers(); condition != "" {
+		labelled.Kind = condition
+		alert.EndsAt = &timestamp
+	}
+	if labelled.InvolvedObject != nil {
+		labelled.Status = "Danger"
+	}
+	kind, namespace, name := labelled.Subject()
+	severity, ok := severities[labelled.Status]
+	if !ok {
+		severity = "info"
+	}
+
+	alert.Labels = map[string]string{
+		"alertname": alertName(labelled),
+		"kind":      kind,
+		"name":      name,
+		"severity":  severity,
+	}
+	if namespace != "" {
+		alert.Labels["namespace"] = namespace
+	}
+	if a.Cluster != "" {
+		alert.Labels["cluster"] = a.Cluster
+	}
+	return alert
+}
+
+func (a *Alertmanager) postAlerts(alerts []*Alert) error {
+	message, err := json.Marshal(alerts)
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+
+	req, err := http.NewRequest("POST", a.Url+"/api/v2/alerts", bytes.NewBuffer(message))
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	if a.Username != "" {
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return delivery.CheckResponse(resp)
+}
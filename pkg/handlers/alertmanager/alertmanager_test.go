@@ -0,0 +1,162 @@
+/*
+Copyright 2018 Bitnami
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package alertmanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+func TestAlertmanagerInit(t *testing.T) {
+	s := &Alertmanager{}
+	expectedError := fmt.Errorf(alertmanagerErrMsg, "Missing Alertmanager url")
+
+	var Tests = []struct {
+		alertmanager config.Alertmanager
+		err          error
+	}{
+		{config.Alertmanager{Url: "http://alertmanager:9093"}, nil},
+		{config.Alertmanager{}, expectedError},
+	}
+
+	for _, tt := range Tests {
+		c := &config.Config{}
+		c.Handler.Alertmanager = tt.alertmanager
+		if err := s.Init(c); !reflect.DeepEqual(err, tt.err) {
+			t.Fatalf("Init(): %v", err)
+		}
+	}
+}
+
+func TestAlertmanagerHandle(t *testing.T) {
+	var Tests = []struct {
+		status    int
+		isErr     bool
+		permanent bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusServiceUnavailable, true, false},
+		{http.StatusBadRequest, true, true},
+	}
+
+	for _, tt := range Tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+
+		s := &Alertmanager{Url: ts.URL}
+		err := s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+		ts.Close()
+
+		if (err != nil) != tt.isErr {
+			t.Fatalf("%d: unexpected error %v", tt.status, err)
+		}
+		if got := delivery.IsPermanent(err); got != tt.permanent {
+			t.Errorf("%d: expected IsPermanent() to be %v, got %v", tt.status, tt.permanent, got)
+		}
+	}
+}
+
+func TestAlertmanagerAlerts(t *testing.T) {
+	var got []Alert
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/alerts" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if user, password, _ := r.BasicAuth(); user != "kubewatch" || password != "secret" {
+			t.Errorf("unexpected credentials %s:%s", user, password)
+		}
+		var alerts []Alert
+		if err := json.NewDecoder(r.Body).Decode(&alerts); err != nil {
+			t.Error(err)
+		}
+		got = append(got, alerts...)
+	}))
+	defer ts.Close()
+
+	now := time.Date(2020, 11, 2, 10, 15, 0, 0, time.UTC)
+	node := api_v1.ObjectReference{Kind: "Node", Name: "node-1"}
+	events := []event.Event{
+		{Name: "node-1.16a7b3c2d1e0f", Namespace: "default", Kind: "NodeNotReady", Reason: "Created", Status: "Danger", InvolvedObject: &node, Timestamp: now},
+		{Name: "node-1.16a7b3c2d1e0f", Namespace: "default", Kind: "NodeNotReady", Reason: "Updated", Status: "Warning", InvolvedObject: &node, Timestamp: now},
+		{Name: "node-1.16a7b3c2d1e10", Namespace: "default", Kind: "NodeReady", Reason: "Created", Status: "Normal", InvolvedObject: &node, Timestamp: now},
+		{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Updated", Status: "Warning", Timestamp: now},
+		// the expiries of k8s Events send nothing
+		{Name: "node-1.16a7b3c2d1e0f", Namespace: "default", Kind: "NodeNotReady", Reason: "Deleted", Status: "Danger", InvolvedObject: &node, Timestamp: now},
+		{Name: "node-1.16a7b3c2d1e10", Namespace: "default", Kind: "NodeReady", Reason: "Deleted", Status: "Danger", InvolvedObject: &node, Timestamp: now},
+	}
+	s := &Alertmanager{Url: ts.URL, Username: "kubewatch", Password: "secret", Cluster: "prod"}
+	for _, e := range events {
+		if err := s.Handle(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nodeLabels := map[string]string{"alertname": "NodeNotReady", "kind": "node", "name": "node-1", "severity": "critical", "cluster": "prod"}
+	var Tests = []struct {
+		labels   map[string]string
+		resolved bool
+	}{
+		{nodeLabels, false},
+		{nodeLabels, false},
+		{nodeLabels, true},
+		{map[string]string{"alertname": "DeploymentUpdated", "kind": "deployment", "namespace": "default", "name": "foo", "severity": "warning", "cluster": "prod"}, false},
+	}
+	if len(got) != len(Tests) {
+		t.Fatalf("expected %d alerts, got %d", len(Tests), len(got))
+	}
+	for i, tt := range Tests {
+		alert := got[i]
+		if !reflect.DeepEqual(alert.Labels, tt.labels) {
+			t.Errorf("%d: expected labels %v, got %v", i, tt.labels, alert.Labels)
+		}
+		if resolved := alert.EndsAt != nil && alert.EndsAt.Equal(now); resolved != tt.resolved {
+			t.Errorf("%d: expected resolved to be %v, got endsAt %v", i, tt.resolved, alert.EndsAt)
+		}
+		if alert.Annotations["message"] != events[i].Message() || !alert.StartsAt.Equal(now) {
+			t.Errorf("%d: unexpected alert %+v", i, alert)
+		}
+	}
+}
+
+func TestAlertName(t *testing.T) {
+	var Tests = []struct {
+		e    event.Event
+		name string
+	}{
+		{event.Event{Kind: "deployment", Reason: "Updated"}, "DeploymentUpdated"},
+		{event.Event{Kind: "pod", Reason: "created"}, "PodCreated"},
+		{event.Event{Kind: "étape", Reason: "Deleted"}, "ÉtapeDeleted"},
+		{event.Event{Kind: "NodeNotReady", Reason: "Created", InvolvedObject: &api_v1.ObjectReference{Kind: "Node"}}, "NodeNotReady"},
+	}
+
+	for _, tt := range Tests {
+		if name := alertName(tt.e); name != tt.name {
+			t.Errorf("alertName(%s %s): expected %s, got %s", tt.e.Kind, tt.e.Reason, tt.name, name)
+		}
+	}
+}
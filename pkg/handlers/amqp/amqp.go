@@ -0,0 +1,220 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amqp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultRoutingKey is the template of the routing keys by default.
+const DefaultRoutingKey = "kubewatch.{{ .Cluster }}.{{ .Namespace }}.{{ .Kind }}.{{ .Reason }}"
+
+// timeout is how long publishing an event waits for the broker to confirm it.
+const timeout = 5 * time.Second
+
+var amqpErrMsg = `
+%s
+
+You need to set the AMQP url
+using "--url/-u" or using environment variables:
+
+export KW_AMQP_URL=amqp_url
+
+Command line flags will override environment variables
+
+`
+
+// AMQP handler implements handler.Handler interface,
+// Publish events as JSON to an AMQP 0-9-1 exchange, e.g. of RabbitMQ
+type AMQP struct {
+	Url      string
+	Exchange string
+	Cluster  string
+
+	renderer   *message.Renderer
+	routingKey *message.Topic
+	config     amqp.Config
+	mu         sync.Mutex
+	conn       *amqp.Connection
+	channel    *amqp.Channel
+	closed     chan *amqp.Error
+	returned   chan amqp.Return
+}
+
+// Init prepares AMQP configuration
+func (a *AMQP) Init(c *config.Config) error {
+	url := c.Handler.AMQP.Url
+	routingKey := c.Handler.AMQP.RoutingKey
+
+	if url == "" {
+		url = os.Getenv("KW_AMQP_URL")
+	}
+	if routingKey == "" {
+		routingKey = DefaultRoutingKey
+	}
+
+	a.Url = url
+	a.Exchange = c.Handler.AMQP.Exchange
+	a.Cluster = c.ClusterName
+
+	if err := checkMissingAMQPVars(a); err != nil {
+		return err
+	}
+
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	a.renderer = renderer
+	if a.routingKey, err = message.NewTopic(routingKey, ".", "*#"); err != nil {
+		return fmt.Errorf("amqp: routingKey: %v", err)
+	}
+	tlsConfig, err := c.Handler.AMQP.TLS.Config()
+	if err != nil {
+		return fmt.Errorf("amqp: tls.%v", err)
+	}
+	if tlsConfig != nil && !strings.HasPrefix(a.Url, "amqps://") {
+		return fmt.Errorf("amqp: tls: enabled with an %s url, expected amqps://", strings.SplitN(a.Url, ":", 2)[0])
+	}
+	a.config = amqp.Config{
+		TLSClientConfig: tlsConfig,
+		Heartbeat:       10 * time.Second,
+		Properties:      amqp.Table{"connection_name": "kubewatch"},
+	}
+	return nil
+}
+
+// Handle handles an event.
+func (a *AMQP) Handle(e event.Event) error {
+	key, err := a.routingKey.Render(e, a.Cluster)
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+	body, err := json.Marshal(webhook.NewMessage(e, a.renderer.Message(e)))
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+	ch, returned, err := a.connect()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	// mandatory, so that the broker returns the messages no queue is bound for
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(ctx, a.Exchange, key, true, false, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    time.Now(),
+		AppId:        "kubewatch",
+		Body:         body,
+	})
+	if err != nil {
+		a.disconnect(ch)
+		return err
+	}
+	if !confirmation.Wait() {
+		if ctx.Err() != nil {
+			a.disconnect(ch)
+			return fmt.Errorf("publishing to %q with key %s: not confirmed within %v", a.Exchange, key, timeout)
+		}
+		return fmt.Errorf("publishing to %q with key %s: rejected by the broker", a.Exchange, key)
+	}
+	// the broker returns a message before confirming it
+	select {
+	case r := <-returned:
+		return fmt.Errorf("publishing to %q with key %s: returned by the broker, %d %s", a.Exchange, key, r.ReplyCode, r.ReplyText)
+	default:
+	}
+
+	log.Printf("Message successfully published to %q with key %s at %s ", a.Exchange, key, time.Now())
+	return nil
+}
+
+func checkMissingAMQPVars(a *AMQP) error {
+	if a.Url == "" {
+		return fmt.Errorf(amqpErrMsg, "Missing AMQP url")
+	}
+
+	return nil
+}
+
+// connect returns a channel in confirm mode and the messages it returns,
+// connecting to the broker on first use, and again once the connection or the
+// channel closed, e.g. when the broker restarted
+func (a *AMQP) connect() (*amqp.Channel, <-chan amqp.Return, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.channel != nil {
+		if !a.channel.IsClosed() {
+			return a.channel, a.returned, nil
+		}
+		var reason interface{} = "closed"
+		select {
+		case err := <-a.closed:
+			if err != nil {
+				reason = err
+			}
+		default:
+		}
+		logrus.Warnf("amqp: channel %v, reconnecting", reason)
+		a.conn.Close()
+		a.conn, a.channel = nil, nil
+	}
+
+	conn, err := amqp.DialConfig(a.Url, a.config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to the broker: %v", err)
+	}
+	ch, err := conn.Channel()
+	if err == nil {
+		err = ch.Confirm(false)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("opening a channel: %v", err)
+	}
+	a.conn, a.channel = conn, ch
+	a.closed = ch.NotifyClose(make(chan *amqp.Error, 1))
+	a.returned = ch.NotifyReturn(make(chan amqp.Return, 1))
+	return ch, a.returned, nil
+}
+
+// disconnect closes the connection of a channel that failed, if still in use
+func (a *AMQP) disconnect(ch *amqp.Channel) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.channel == ch {
+		a.conn.Close()
+		a.conn, a.channel = nil, nil
+	}
+}
@@ -0,0 +1,412 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package amqp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+)
+
+// frame types and end marker of AMQP 0-9-1
+const (
+	frameMethod    = 1
+	frameHeader    = 2
+	frameBody      = 3
+	frameHeartbeat = 8
+	frameEnd       = 0xCE
+)
+
+// publishing is a message published to the broker
+type publishing struct {
+	exchange     string
+	routingKey   string
+	mandatory    bool
+	contentType  string
+	deliveryMode uint8
+	body         []byte
+}
+
+// broker is a minimal AMQP 0-9-1 broker recording the published messages and
+// confirming them, except to the exchanges it does not know, returning the
+// mandatory ones published with an unroutable key
+type broker struct {
+	listener   net.Listener
+	exchanges  map[string]bool
+	unroutable map[string]bool
+
+	mu        sync.Mutex
+	conns     []net.Conn
+	published []publishing
+}
+
+func newBroker(t *testing.T, exchanges ...string) *broker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &broker{listener: listener, exchanges: map[string]bool{"": true}, unroutable: map[string]bool{}}
+	for _, exchange := range exchanges {
+		b.exchanges[exchange] = true
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns = append(b.conns, conn)
+			b.mu.Unlock()
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *broker) url() string {
+	return "amqp://guest:guest@" + b.listener.Addr().String() + "/"
+}
+
+// restart drops the connections, as a broker restarting does
+func (b *broker) restart() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, conn := range b.conns {
+		conn.Close()
+	}
+	b.conns = nil
+}
+
+func (b *broker) messages() []publishing {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]publishing(nil), b.published...)
+}
+
+func (b *broker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	if _, err := io.ReadFull(r, make([]byte, 8)); err != nil {
+		return
+	}
+	// connection.start: version 0-9, no server properties, PLAIN, en_US
+	writeMethod(conn, 0, 10, 10, []byte{0, 9}, uint32(0), longstr("PLAIN"), longstr("en_US"))
+
+	var (
+		pending *publishing
+		size    uint64
+		tags    = map[uint16]uint64{}
+	)
+	for {
+		typ, channel, payload, err := readFrame(r)
+		if err != nil {
+			return
+		}
+		switch typ {
+		case frameHeader:
+			// class, weight, body size, property flags, properties
+			size = binary.BigEndian.Uint64(payload[4:12])
+			flags := binary.BigEndian.Uint16(payload[12:14])
+			props := payload[14:]
+			if flags&0x8000 != 0 {
+				pending.contentType, props = shortstr(props)
+			}
+			if flags&0x4000 != 0 {
+				_, props = shortstr(props)
+			}
+			if flags&0x1000 != 0 && flags&0x2000 == 0 {
+				pending.deliveryMode = props[0]
+			}
+		case frameBody:
+			pending.body = append(pending.body, payload...)
+		case frameMethod:
+			class, method := binary.BigEndian.Uint16(payload), binary.BigEndian.Uint16(payload[2:])
+			args := payload[4:]
+			switch {
+			case class == 10 && method == 11: // connection.start-ok
+				writeMethod(conn, 0, 10, 30, uint16(0), uint32(131072), uint16(0))
+			case class == 10 && method == 40: // connection.open
+				writeMethod(conn, 0, 10, 41, shortstrArg(""))
+			case class == 10 && method == 50: // connection.close
+				writeMethod(conn, 0, 10, 51)
+				return
+			case class == 20 && method == 10: // channel.open
+				writeMethod(conn, channel, 20, 11, uint32(0))
+			case class == 20 && method == 40: // channel.close
+				writeMethod(conn, channel, 20, 41)
+			case class == 85 && method == 10: // confirm.select
+				writeMethod(conn, channel, 85, 11)
+			case class == 60 && method == 40: // basic.publish
+				pending = &publishing{}
+				pending.exchange, args = shortstr(args[2:])
+				pending.routingKey, args = shortstr(args)
+				pending.mandatory = args[0]&1 != 0
+				size = 0
+			}
+		}
+		if pending != nil && typ != frameMethod && uint64(len(pending.body)) == size {
+			if !b.exchanges[pending.exchange] {
+				// channel.close: 404 NOT_FOUND, as brokers do
+				writeMethod(conn, channel, 20, 40, uint16(404), shortstrArg("NOT_FOUND - no exchange"), uint16(60), uint16(40))
+			} else if pending.mandatory && b.unroutable[pending.routingKey] {
+				// basic.return: 312 NO_ROUTE, with the message, then basic.ack
+				writeMethod(conn, channel, 60, 50, uint16(312), shortstrArg("NO_ROUTE"), shortstrArg(pending.exchange), shortstrArg(pending.routingKey))
+				header := make([]byte, 14) // class, weight, body size, no properties
+				binary.BigEndian.PutUint16(header, 60)
+				binary.BigEndian.PutUint64(header[4:], uint64(len(pending.body)))
+				writeFrame(conn, frameHeader, channel, header)
+				writeFrame(conn, frameBody, channel, pending.body)
+				tags[channel]++
+				writeMethod(conn, channel, 60, 80, tags[channel], uint8(0))
+			} else {
+				b.mu.Lock()
+				b.published = append(b.published, *pending)
+				b.mu.Unlock()
+				tags[channel]++
+				// basic.ack
+				writeMethod(conn, channel, 60, 80, tags[channel], uint8(0))
+			}
+			pending = nil
+		}
+	}
+}
+
+type shortstrArg string
+
+type longstr string
+
+func readFrame(r *bufio.Reader) (byte, uint16, []byte, error) {
+	for {
+		header := make([]byte, 7)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return 0, 0, nil, err
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[3:])+1)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return 0, 0, nil, err
+		}
+		if header[0] != frameHeartbeat {
+			return header[0], binary.BigEndian.Uint16(header[1:]), payload[:len(payload)-1], nil
+		}
+	}
+}
+
+func writeMethod(w io.Writer, channel, class, method uint16, args ...interface{}) {
+	var payload bytes.Buffer
+	binary.Write(&payload, binary.BigEndian, class)
+	binary.Write(&payload, binary.BigEndian, method)
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case []byte:
+			payload.Write(arg)
+		case shortstrArg:
+			payload.WriteByte(byte(len(arg)))
+			payload.WriteString(string(arg))
+		case longstr:
+			binary.Write(&payload, binary.BigEndian, uint32(len(arg)))
+			payload.WriteString(string(arg))
+		default:
+			binary.Write(&payload, binary.BigEndian, arg)
+		}
+	}
+	writeFrame(w, frameMethod, channel, payload.Bytes())
+}
+
+func writeFrame(w io.Writer, typ byte, channel uint16, payload []byte) {
+	var frame bytes.Buffer
+	frame.WriteByte(typ)
+	binary.Write(&frame, binary.BigEndian, channel)
+	binary.Write(&frame, binary.BigEndian, uint32(len(payload)))
+	frame.Write(payload)
+	frame.WriteByte(frameEnd)
+	w.Write(frame.Bytes())
+}
+
+func shortstr(b []byte) (string, []byte) {
+	n := int(b[0])
+	return string(b[1 : 1+n]), b[1+n:]
+}
+
+func TestAMQPInit(t *testing.T) {
+	s := &AMQP{}
+	expectedError := fmt.Errorf(amqpErrMsg, "Missing AMQP url")
+
+	var Tests = []struct {
+		amqp config.AMQP
+		err  error
+	}{
+		{config.AMQP{Url: "amqp://localhost:5672"}, nil},
+		{config.AMQP{}, expectedError},
+	}
+
+	for _, tt := range Tests {
+		c := &config.Config{}
+		c.Handler.AMQP = tt.amqp
+		if err := s.Init(c); !reflect.DeepEqual(err, tt.err) {
+			t.Fatalf("Init(): %v", err)
+		}
+	}
+}
+
+func TestAMQPPublish(t *testing.T) {
+	e := event.Event{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Updated", Status: "Warning"}
+
+	var Tests = []struct {
+		amqp       config.AMQP
+		routingKey string
+	}{
+		{config.AMQP{}, "kubewatch.prod.default.deployment.Updated"},
+		{config.AMQP{Exchange: "events", RoutingKey: "{{ .Kind }}.{{ .Name }}"}, "deployment.foo"},
+	}
+
+	for _, tt := range Tests {
+		b := newBroker(t, "events")
+		c := &config.Config{ClusterName: "prod"}
+		c.Handler.AMQP = tt.amqp
+		c.Handler.AMQP.Url = b.url()
+		s := &AMQP{}
+		if err := s.Init(c); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Handle(e); err != nil {
+			t.Fatalf("%s: %v", tt.routingKey, err)
+		}
+		s.conn.Close()
+		b.listener.Close()
+
+		published := b.messages()
+		if len(published) != 1 {
+			t.Fatalf("%s: expected 1 message, got %d", tt.routingKey, len(published))
+		}
+		m := published[0]
+		if m.exchange != tt.amqp.Exchange || m.routingKey != tt.routingKey {
+			t.Errorf("%s: published to %q with key %s", tt.routingKey, m.exchange, m.routingKey)
+		}
+		if m.contentType != "application/json" || m.deliveryMode != 2 {
+			t.Errorf("%s: unexpected content type %q or delivery mode %d", tt.routingKey, m.contentType, m.deliveryMode)
+		}
+		var msg webhook.WebhookMessage
+		if err := json.Unmarshal(m.body, &msg); err != nil {
+			t.Fatalf("%s: %v", tt.routingKey, err)
+		}
+		if msg.EventMeta.Name != "foo" || msg.Text != e.Message() {
+			t.Errorf("%s: unexpected message %+v", tt.routingKey, msg)
+		}
+	}
+}
+
+func TestAMQPRecovery(t *testing.T) {
+	e := event.Event{Name: "foo", Namespace: "default", Kind: "pod", Reason: "Created"}
+	b := newBroker(t)
+	defer b.listener.Close()
+
+	c := &config.Config{}
+	c.Handler.AMQP.Url = b.url()
+	s := &AMQP{}
+	if err := s.Init(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Handle(e); err != nil {
+		t.Fatal(err)
+	}
+
+	b.restart()
+	for deadline := time.Now().Add(time.Second); !s.conn.IsClosed() && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := s.Handle(e); err != nil {
+		t.Fatalf("expected the handler to reconnect, got %v", err)
+	}
+
+	// the broker closes the channel on unknown exchanges
+	s.Exchange = "unknown"
+	if err := s.Handle(e); err == nil || delivery.IsPermanent(err) {
+		t.Errorf("expected a transient error, got %v", err)
+	}
+	s.Exchange = ""
+	if err := s.Handle(e); err != nil {
+		t.Fatalf("expected the handler to reopen the channel, got %v", err)
+	}
+	s.conn.Close()
+
+	if published := b.messages(); len(published) != 3 {
+		t.Errorf("expected 3 messages, got %d", len(published))
+	}
+}
+
+func TestAMQPUnroutable(t *testing.T) {
+	e := event.Event{Name: "foo", Namespace: "default", Kind: "pod", Reason: "Created"}
+	b := newBroker(t, "events")
+	defer b.listener.Close()
+	b.unroutable["pod.foo"] = true
+
+	c := &config.Config{}
+	c.Handler.AMQP = config.AMQP{Url: b.url(), Exchange: "events", RoutingKey: "{{ .Kind }}.{{ .Name }}"}
+	s := &AMQP{}
+	if err := s.Init(c); err != nil {
+		t.Fatal(err)
+	}
+	err := s.Handle(e)
+	if err == nil || delivery.IsPermanent(err) {
+		t.Fatalf("expected a transient error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "NO_ROUTE") {
+		t.Errorf("expected the reply text in %v", err)
+	}
+
+	// the next messages are delivered on the same channel
+	delete(b.unroutable, "pod.foo")
+	if err := s.Handle(e); err != nil {
+		t.Fatal(err)
+	}
+	s.conn.Close()
+
+	if published := b.messages(); len(published) != 1 || !published[0].mandatory {
+		t.Errorf("expected 1 mandatory message, got %+v", published)
+	}
+}
+
+func TestAMQPUnavailable(t *testing.T) {
+	b := newBroker(t)
+	b.listener.Close()
+
+	c := &config.Config{}
+	c.Handler.AMQP.Url = b.url()
+	s := &AMQP{}
+	if err := s.Init(c); err != nil {
+		t.Fatalf("expected Init() to succeed without broker, got %v", err)
+	}
+	err := s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+	if err == nil || delivery.IsPermanent(err) {
+		t.Errorf("expected a transient error, got %v", err)
+	}
+}
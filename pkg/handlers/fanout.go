@@ -0,0 +1,104 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/mkmik/multierror"
+	"github.com/sirupsen/logrus"
+)
+
+// Fanout handler implements Handler interface,
+// delivering each event to all of its member handlers
+type Fanout struct {
+	Handlers []Handler
+}
+
+// NewFanout returns a handler that fans out events to the given handlers
+func NewFanout(handlers ...Handler) *Fanout {
+	return &Fanout{Handlers: handlers}
+}
+
+// Init initializes every member handler
+func (f *Fanout) Init(c *config.Config) error {
+	for _, h := range f.Handlers {
+		if err := h.Init(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handle delivers the event to all member handlers concurrently.
+// A failing or panicking handler does not prevent delivery to the others,
+// the errors of all failing handlers are returned together.
+func (f *Fanout) Handle(e event.Event) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, h := range f.Handlers {
+		wg.Add(1)
+		go func(h Handler) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					logrus.Errorf("handler %s panicked: %v", handlerName(h), r)
+				}
+			}()
+			if err := h.Handle(e); err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, multierror.Tag(handlerName(h), err))
+			}
+		}(h)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return multierror.Join(errs)
+	}
+	return nil
+}
+
+// Close closes every member handler
+func (f *Fanout) Close() error {
+	var errs []error
+	for _, h := range f.Handlers {
+		if err := Close(h); err != nil {
+			errs = append(errs, multierror.Tag(handlerName(h), err))
+		}
+	}
+	if len(errs) > 0 {
+		return multierror.Join(errs)
+	}
+	return nil
+}
+
+func handlerName(h Handler) string {
+	switch h := h.(type) {
+	case *Instance:
+		return h.Name
+	case *Retry:
+		return h.name
+	}
+	return fmt.Sprintf("%T", h)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+type recorder struct {
+	mu        sync.Mutex
+	initErr   error
+	handleErr error
+	panics    bool
+	events    []event.Event
+}
+
+func (r *recorder) Init(c *config.Config) error {
+	return r.initErr
+}
+
+func (r *recorder) Handle(e event.Event) error {
+	if r.panics {
+		panic("boom")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return r.handleErr
+}
+
+func TestFanoutInit(t *testing.T) {
+	expectedError := fmt.Errorf("bad config")
+
+	var Tests = []struct {
+		handlers []Handler
+		err      error
+	}{
+		{[]Handler{&recorder{}, &recorder{}}, nil},
+		{[]Handler{&recorder{}, &recorder{initErr: expectedError}}, expectedError},
+	}
+
+	for _, tt := range Tests {
+		f := NewFanout(tt.handlers...)
+		if err := f.Init(&config.Config{}); !reflect.DeepEqual(err, tt.err) {
+			t.Fatalf("Init(): %v", err)
+		}
+	}
+}
+
+func TestFanoutHandle(t *testing.T) {
+	first := &recorder{}
+	broken := &recorder{panics: true}
+	last := &recorder{}
+
+	e := event.Event{
+		Name:      "foo",
+		Namespace: "new",
+		Kind:      "pod",
+		Reason:    "Created",
+		Status:    "Normal",
+	}
+
+	if err := NewFanout(first, broken, last).Handle(e); err != nil {
+		t.Fatalf("Handle(): %v", err)
+	}
+
+	for i, r := range []*recorder{first, last} {
+		if !reflect.DeepEqual(r.events, []event.Event{e}) {
+			t.Errorf("handler %d: expected %v, got %v", i, []event.Event{e}, r.events)
+		}
+	}
+}
+
+func TestFanoutHandleErrors(t *testing.T) {
+	rejected := delivery.Permanent(fmt.Errorf("rejected"))
+	unavailable := fmt.Errorf("unavailable")
+
+	var Tests = []struct {
+		name      string
+		handlers  []Handler
+		isErr     bool
+		permanent bool
+	}{
+		{"no failure", []Handler{&recorder{}, &recorder{}}, false, false},
+		{"permanent failure", []Handler{&recorder{}, &recorder{handleErr: rejected}}, true, true},
+		{"mixed failures", []Handler{&recorder{handleErr: unavailable}, &recorder{handleErr: rejected}}, true, false},
+	}
+
+	for _, tt := range Tests {
+		err := NewFanout(tt.handlers...).Handle(event.Event{Name: "foo"})
+		if (err != nil) != tt.isErr {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		if got := delivery.IsPermanent(err); got != tt.permanent {
+			t.Errorf("%s: expected IsPermanent() to be %v, got %v", tt.name, tt.permanent, got)
+		}
+	}
+}
@@ -27,7 +27,9 @@ import (
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
 )
 
 var flockColors = map[string]string{
@@ -52,6 +54,8 @@ Command line flags will override environment variables
 // Notify event to Flock channel
 type Flock struct {
 	Url string
+
+	renderer *message.Renderer
 }
 
 // FlockMessage struct
@@ -83,20 +87,26 @@ func (f *Flock) Init(c *config.Config) error {
 
 	f.Url = url
 
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	f.renderer = renderer
+
 	return checkMissingFlockVars(f)
 }
 
 // Handle handles an event.
-func (f *Flock) Handle(e event.Event) {
+func (f *Flock) Handle(e event.Event) error {
 	flockMessage := prepareFlockMessage(e, f)
 
 	err := postMessage(f.Url, flockMessage)
 	if err != nil {
-		log.Printf("%s\n", err)
-		return
+		return err
 	}
 
 	log.Printf("Message successfully sent to channel %s at %s", f.Url, time.Now())
+	return nil
 }
 
 func checkMissingFlockVars(s *Flock) error {
@@ -113,7 +123,7 @@ func prepareFlockMessage(e event.Event, f *Flock) *FlockMessage {
 		Notification: "Kubewatch Alert",
 		Attachements: []FlockMessageAttachement{
 			{
-				Title: e.Message(),
+				Title: f.renderer.Message(e),
 				Color: flockColors[e.Status],
 			},
 		},
@@ -123,20 +133,21 @@ func prepareFlockMessage(e event.Event, f *Flock) *FlockMessage {
 func postMessage(url string, flockMessage *FlockMessage) error {
 	message, err := json.Marshal(flockMessage)
 	if err != nil {
-		return err
+		return delivery.Permanent(err)
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
 	if err != nil {
-		return err
+		return delivery.Permanent(err)
 	}
 	req.Header.Add("Content-Type", "application/json")
 
 	client := &http.Client{}
-	_, err = client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	return delivery.CheckResponse(resp)
 }
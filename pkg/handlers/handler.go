@@ -17,34 +17,113 @@ limitations under the License.
 package handlers
 
 import (
+	"fmt"
+	"io"
+	"reflect"
+
 	"github.com/bitnami-labs/kubewatch/config"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/alertmanager"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/amqp"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/flock"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/hipchat"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/kafka"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/mattermost"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/mqtt"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/msteam"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/nats"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/opsgenie"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/pagerduty"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/redis"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/slack"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/smtp"
 	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
 )
 
 // Handler is implemented by any handler.
-// The Handle method is used to process event
+// The Handle method is used to process event, it returns an error when
+// the event could not be delivered; see package delivery for permanent
+// failures and requested retry delays
 type Handler interface {
 	Init(c *config.Config) error
-	Handle(e event.Event)
+	Handle(e event.Event) error
+}
+
+// Batcher is implemented by the handlers sending events in batches; the
+// events of a batch are handled concurrently, each Handle call waiting for
+// the batch to be sent
+type Batcher interface {
+	BatchSize() int
 }
 
 // Map maps each event handler function to a name for easily lookup
 var Map = map[string]interface{}{
-	"default":    &Default{},
-	"slack":      &slack.Slack{},
-	"hipchat":    &hipchat.Hipchat{},
-	"mattermost": &mattermost.Mattermost{},
-	"flock":      &flock.Flock{},
-	"webhook":    &webhook.Webhook{},
-	"ms-teams":   &msteam.MSTeams{},
-	"smtp":       &smtp.SMTP{},
+	"default":      &Default{},
+	"slack":        &slack.Slack{},
+	"hipchat":      &hipchat.Hipchat{},
+	"mattermost":   &mattermost.Mattermost{},
+	"flock":        &flock.Flock{},
+	"webhook":      &webhook.Webhook{},
+	"ms-teams":     &msteam.MSTeams{},
+	"msteams":      &msteam.MSTeams{},
+	"smtp":         &smtp.SMTP{},
+	"pagerduty":    &pagerduty.PagerDuty{},
+	"opsgenie":     &opsgenie.Opsgenie{},
+	"alertmanager": &alertmanager.Alertmanager{},
+	"kafka":        &kafka.Kafka{},
+	"nats":         &nats.NATS{},
+	"amqp":         &amqp.AMQP{},
+	"mqtt":         &mqtt.MQTT{},
+	"redis":        &redis.Redis{},
+}
+
+// New returns a new, uninitialized handler of the type registered under the given name in Map
+func New(name string) (Handler, error) {
+	h, ok := Map[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown handler type %q", name)
+	}
+	return reflect.New(reflect.TypeOf(h).Elem()).Interface().(Handler), nil
+}
+
+// Instance binds a handler to the configuration of a named handler,
+// so that several handlers of the same type can be configured differently
+type Instance struct {
+	Handler
+	Name   string
+	Config *config.Config
+}
+
+// NewInstance returns a new handler for a named handler configuration
+func NewInstance(c *config.Config, h config.NamedHandler) (*Instance, error) {
+	handler, err := New(h.Type)
+	if err != nil {
+		return nil, fmt.Errorf("handler %q: %v", h.Name, err)
+	}
+	instanceConfig := *c
+	instanceConfig.Handler = h.Handler
+	instanceConfig.Template = c.Template.Merge(h.Template)
+	return &Instance{Handler: handler, Name: h.Name, Config: &instanceConfig}, nil
+}
+
+// Init initializes the handler with its own configuration
+func (i *Instance) Init(c *config.Config) error {
+	if err := i.Handler.Init(i.Config); err != nil {
+		return fmt.Errorf("handler %q: %v", i.Name, err)
+	}
+	return nil
+}
+
+// Close releases the resources of a handler once it is done delivering events,
+// e.g. flushing the messages it buffers, if it implements io.Closer
+func Close(h Handler) error {
+	if i, ok := h.(*Instance); ok {
+		h = i.Handler
+	}
+	if c, ok := h.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 // Default handler implements Handler interface,
@@ -59,5 +138,6 @@ func (d *Default) Init(c *config.Config) error {
 }
 
 // Handle handles an event.
-func (d *Default) Handle(e event.Event) {
+func (d *Default) Handle(e event.Event) error {
+	return nil
 }
@@ -0,0 +1,63 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/slack"
+)
+
+func TestNew(t *testing.T) {
+	a, err := New("slack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := New("slack")
+	if _, ok := a.(*slack.Slack); !ok {
+		t.Fatalf("expected a *slack.Slack, got %T", a)
+	}
+	if a == b {
+		t.Errorf("expected distinct handlers for each call")
+	}
+	if _, err := New("carrier-pigeon"); err == nil {
+		t.Errorf("expected an error for an unknown handler type")
+	}
+}
+
+func TestNewInstance(t *testing.T) {
+	c := &config.Config{Namespace: "default"}
+	c.Handler.Slack.Token = "legacy"
+
+	named := config.NamedHandler{Name: "sre", Type: "slack"}
+	named.Handler.Slack.Token = "sre"
+
+	i, err := NewInstance(c, named)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i.Config.Handler.Slack.Token != "sre" || i.Config.Namespace != "default" {
+		t.Errorf("unexpected instance configuration %+v", i.Config)
+	}
+	if c.Handler.Slack.Token != "legacy" {
+		t.Errorf("expected the shared configuration to be left untouched")
+	}
+	if handlerName(i) != "sre" {
+		t.Errorf("expected the instance to be named sre, got %s", handlerName(i))
+	}
+}
@@ -26,7 +26,9 @@ import (
 	"net/url"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
 )
 
 var hipchatColors = map[string]hipchat.Color{
@@ -55,6 +57,8 @@ type Hipchat struct {
 	Token string
 	Room  string
 	Url   string
+
+	renderer *message.Renderer
 }
 
 // Init prepares hipchat configuration
@@ -79,29 +83,41 @@ func (s *Hipchat) Init(c *config.Config) error {
 	s.Room = room
 	s.Url = url
 
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	s.renderer = renderer
+
 	return checkMissingHipchatVars(s)
 }
 
 // Handle handles the notification.
-func (s *Hipchat) Handle(e event.Event) {
+func (s *Hipchat) Handle(e event.Event) error {
 	client := hipchat.NewClient(s.Token)
 	if s.Url != "" {
 		baseUrl, err := url.Parse(s.Url)
 		if err != nil {
-			panic(err)
+			return delivery.Permanent(err)
 		}
 		client.BaseURL = baseUrl
 	}
 
-	notificationRequest := prepareHipchatNotification(e)
-	_, err := client.Room.Notification(s.Room, &notificationRequest)
+	notificationRequest := prepareHipchatNotification(e, s)
+	resp, err := client.Room.Notification(s.Room, &notificationRequest)
 
 	if err != nil {
-		log.Printf("%s\n", err)
-		return
+		if resp != nil {
+			defer resp.Body.Close()
+			if respErr := delivery.CheckResponse(resp); respErr != nil {
+				return respErr
+			}
+		}
+		return err
 	}
 
 	log.Printf("Message successfully sent to room %s", s.Room)
+	return nil
 }
 
 func checkMissingHipchatVars(s *Hipchat) error {
@@ -112,9 +128,9 @@ func checkMissingHipchatVars(s *Hipchat) error {
 	return nil
 }
 
-func prepareHipchatNotification(e event.Event) hipchat.NotificationRequest {
+func prepareHipchatNotification(e event.Event, s *Hipchat) hipchat.NotificationRequest {
 	notification := hipchat.NotificationRequest{
-		Message: e.Message(),
+		Message: s.renderer.Message(e),
 		Notify:  true,
 		From:    "kubewatch",
 	}
@@ -0,0 +1,294 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+)
+
+// defaultBatchTimeout is how long a batch waits for more messages by default.
+const defaultBatchTimeout = time.Second
+
+var kafkaErrMsg = `
+%s
+
+You need to set the Kafka brokers and topic
+using "--brokers/-b" and "--topic/-t" or using environment variables:
+
+export KW_KAFKA_BROKERS=kafka_brokers (comma-separated)
+export KW_KAFKA_TOPIC=kafka_topic
+
+Command line flags will override environment variables
+
+`
+
+var requiredAcks = map[string]sarama.RequiredAcks{
+	"":       sarama.WaitForAll,
+	"all":    sarama.WaitForAll,
+	"leader": sarama.WaitForLocal,
+	"none":   sarama.NoResponse,
+}
+
+var compressions = map[string]sarama.CompressionCodec{
+	"":       sarama.CompressionNone,
+	"none":   sarama.CompressionNone,
+	"gzip":   sarama.CompressionGZIP,
+	"snappy": sarama.CompressionSnappy,
+	"lz4":    sarama.CompressionLZ4,
+	"zstd":   sarama.CompressionZSTD,
+}
+
+// Kafka handler implements handler.Handler interface,
+// Produce events as JSON to a Kafka topic, keyed by object
+type Kafka struct {
+	Brokers []string
+	Topic   string
+
+	renderer *message.Renderer
+	conf     *sarama.Config
+	mu       sync.Mutex
+	producer sarama.AsyncProducer
+	flushed  chan struct{}
+}
+
+// Init prepares Kafka configuration and connects to the brokers
+func (k *Kafka) Init(c *config.Config) error {
+	brokers := c.Handler.Kafka.Brokers
+	topic := c.Handler.Kafka.Topic
+
+	if len(brokers) == 0 && os.Getenv("KW_KAFKA_BROKERS") != "" {
+		brokers = strings.Split(os.Getenv("KW_KAFKA_BROKERS"), ",")
+	}
+	if topic == "" {
+		topic = os.Getenv("KW_KAFKA_TOPIC")
+	}
+
+	k.Brokers = brokers
+	k.Topic = topic
+
+	if err := checkMissingKafkaVars(k); err != nil {
+		return err
+	}
+
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	k.renderer = renderer
+
+	conf, err := producerConfig(c.Handler.Kafka)
+	if err != nil {
+		return fmt.Errorf("kafka: %v", err)
+	}
+	k.conf = conf
+	return nil
+}
+
+// Handle handles an event.
+func (k *Kafka) Handle(e event.Event) error {
+	msg, err := prepareKafkaMessage(e, k)
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+	producer, err := k.connect()
+	if err != nil {
+		return err
+	}
+
+	result := make(chan error, 1)
+	msg.Metadata = result
+	producer.Input() <- msg
+	if err := <-result; err != nil {
+		return err
+	}
+
+	log.Printf("Message %s successfully sent to %s at %s ", msg.Key, k.Topic, time.Now())
+	return nil
+}
+
+// Close closes the producer, if any, once the messages it buffers are sent,
+// i.e. within batchTimeout
+func (k *Kafka) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.producer == nil {
+		return nil
+	}
+	k.producer.AsyncClose()
+	// the producer closes its channels once flushed
+	<-k.flushed
+	k.producer = nil
+	return nil
+}
+
+// BatchSize returns how many messages are sent at once, so that a batch fills
+// up while its messages wait to be acknowledged
+func (k *Kafka) BatchSize() int {
+	return k.conf.Producer.Flush.Messages
+}
+
+func checkMissingKafkaVars(k *Kafka) error {
+	if len(k.Brokers) == 0 || k.Topic == "" {
+		return fmt.Errorf(kafkaErrMsg, "Missing Kafka brokers or topic")
+	}
+
+	return nil
+}
+
+// connect returns the producer, connecting to the brokers on first use so
+// that kubewatch starts while they are unavailable
+func (k *Kafka) connect() (sarama.AsyncProducer, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.producer != nil {
+		return k.producer, nil
+	}
+	producer, err := sarama.NewAsyncProducer(k.Brokers, k.conf)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %v", strings.Join(k.Brokers, ","), err)
+	}
+	k.producer = producer
+	k.flushed = make(chan struct{})
+	go k.results(producer, k.flushed)
+	return producer, nil
+}
+
+// producerConfig returns the configuration of the producer
+func producerConfig(c config.Kafka) (*sarama.Config, error) {
+	conf := sarama.NewConfig()
+	conf.ClientID = "kubewatch"
+	conf.Producer.Return.Successes = true
+
+	if c.Version != "" {
+		version, err := sarama.ParseKafkaVersion(c.Version)
+		if err != nil {
+			return nil, fmt.Errorf("version: %v", err)
+		}
+		conf.Version = version
+	}
+	acks, ok := requiredAcks[c.RequiredAcks]
+	if !ok {
+		return nil, fmt.Errorf("requiredAcks: unknown value %q, expected none, leader or all", c.RequiredAcks)
+	}
+	conf.Producer.RequiredAcks = acks
+	compression, ok := compressions[c.Compression]
+	if !ok {
+		return nil, fmt.Errorf("compression: unknown codec %q, expected none, gzip, snappy, lz4 or zstd", c.Compression)
+	}
+	conf.Producer.Compression = compression
+
+	if c.BatchSize > 1 {
+		timeout := defaultBatchTimeout
+		if c.BatchTimeout != "" {
+			d, err := time.ParseDuration(c.BatchTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("batchTimeout: %v", err)
+			}
+			timeout = d
+		}
+		conf.Producer.Flush.Messages = c.BatchSize
+		conf.Producer.Flush.Frequency = timeout
+	}
+
+	tlsConfig, err := c.TLS.Config()
+	if err != nil {
+		return nil, fmt.Errorf("tls.%v", err)
+	}
+	if tlsConfig != nil {
+		conf.Net.TLS.Enable = true
+		conf.Net.TLS.Config = tlsConfig
+	}
+
+	switch c.SASL.Mechanism {
+	case "":
+	case sarama.SASLTypePlaintext:
+	case sarama.SASLTypeSCRAMSHA256:
+		conf.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hash: sha256Hash} }
+	case sarama.SASLTypeSCRAMSHA512:
+		conf.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient { return &scramClient{hash: sha512Hash} }
+	default:
+		return nil, fmt.Errorf("sasl.mechanism: unknown mechanism %q, expected PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512", c.SASL.Mechanism)
+	}
+	if c.SASL.Mechanism != "" {
+		conf.Net.SASL.Enable = true
+		conf.Net.SASL.Mechanism = sarama.SASLMechanism(c.SASL.Mechanism)
+		conf.Net.SASL.User = c.SASL.Username
+		conf.Net.SASL.Password = c.SASL.Password
+	}
+
+	return conf, conf.Validate()
+}
+
+// messageKey keys the messages by the object they are about, so that the
+// messages of an object land in the same partition, in order.
+func messageKey(e event.Event) string {
+	kind, namespace, name := e.Subject()
+	return strings.Join([]string{namespace, kind, name}, "/")
+}
+
+func prepareKafkaMessage(e event.Event, k *Kafka) (*sarama.ProducerMessage, error) {
+	value, err := json.Marshal(webhook.NewMessage(e, k.renderer.Message(e)))
+	if err != nil {
+		return nil, err
+	}
+	return &sarama.ProducerMessage{
+		Topic: k.Topic,
+		Key:   sarama.StringEncoder(messageKey(e)),
+		Value: sarama.ByteEncoder(value),
+	}, nil
+}
+
+// results reports the outcome of the messages to the Handle calls waiting for
+// them, and tells once the producer is closed.
+func (k *Kafka) results(producer sarama.AsyncProducer, flushed chan struct{}) {
+	defer close(flushed)
+	successes, errors := producer.Successes(), producer.Errors()
+	for successes != nil || errors != nil {
+		select {
+		case msg, ok := <-successes:
+			if !ok {
+				successes = nil
+				continue
+			}
+			msg.Metadata.(chan error) <- nil
+		case perr, ok := <-errors:
+			if !ok {
+				errors = nil
+				continue
+			}
+			err := perr.Err
+			switch err {
+			case sarama.ErrMessageSizeTooLarge, sarama.ErrInvalidMessage:
+				err = delivery.Permanent(err)
+			}
+			perr.Msg.Metadata.(chan error) <- err
+		}
+	}
+}
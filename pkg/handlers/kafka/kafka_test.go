@@ -0,0 +1,207 @@
+/*
+Copyright 2018 Bitnami
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+)
+
+func TestKafkaInit(t *testing.T) {
+	s := &Kafka{}
+	expectedError := fmt.Errorf(kafkaErrMsg, "Missing Kafka brokers or topic")
+
+	var Tests = []struct {
+		kafka config.Kafka
+		err   error
+	}{
+		{config.Kafka{Brokers: []string{"localhost:9092"}, Topic: "kubewatch"}, nil},
+		{config.Kafka{Brokers: []string{"localhost:9092"}}, expectedError},
+		{config.Kafka{Topic: "kubewatch"}, expectedError},
+	}
+
+	for _, tt := range Tests {
+		c := &config.Config{}
+		c.Handler.Kafka = tt.kafka
+		if err := s.Init(c); !reflect.DeepEqual(err, tt.err) {
+			t.Fatalf("Init(): %v", err)
+		}
+	}
+}
+
+func TestProducerConfig(t *testing.T) {
+	var Tests = []struct {
+		kafka config.Kafka
+		isErr bool
+	}{
+		{config.Kafka{}, false},
+		{config.Kafka{Version: "2.6.0", RequiredAcks: "leader", Compression: "zstd", BatchSize: 100, BatchTimeout: "500ms"}, false},
+		{config.Kafka{SASL: config.SASL{Mechanism: "SCRAM-SHA-512", Username: "kubewatch", Password: "secret"}}, false},
+		{config.Kafka{Version: "latest"}, true},
+		{config.Kafka{RequiredAcks: "some"}, true},
+		{config.Kafka{Compression: "brotli"}, true},
+		{config.Kafka{BatchSize: 100, BatchTimeout: "soon"}, true},
+		{config.Kafka{SASL: config.SASL{Mechanism: "GSSAPI"}}, true},
+		{config.Kafka{TLS: config.TLS{Enabled: true, CAFile: "/nonexistent/ca.pem"}}, true},
+	}
+
+	for _, tt := range Tests {
+		if _, err := producerConfig(tt.kafka); (err != nil) != tt.isErr {
+			t.Errorf("%+v: unexpected error %v", tt.kafka, err)
+		}
+	}
+}
+
+func TestKafkaMessage(t *testing.T) {
+	k := &Kafka{Topic: "kubewatch"}
+	e := event.Event{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Updated", Status: "Warning"}
+	msg, err := prepareKafkaMessage(e, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Topic != "kubewatch" || msg.Key != sarama.StringEncoder("default/deployment/foo") {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	var value webhook.WebhookMessage
+	if err := json.Unmarshal(msg.Value.(sarama.ByteEncoder), &value); err != nil {
+		t.Fatal(err)
+	}
+	if value.EventMeta.Kind != "deployment" || value.EventMeta.Name != "foo" || value.Text != e.Message() {
+		t.Errorf("unexpected value %+v", value)
+	}
+}
+
+func TestKafkaHandle(t *testing.T) {
+	var Tests = []struct {
+		kerr      sarama.KError
+		batchSize int
+		isErr     bool
+		permanent bool
+	}{
+		{sarama.ErrNoError, 0, false, false},
+		{sarama.ErrNotEnoughReplicas, 0, true, false},
+		{sarama.ErrMessageSizeTooLarge, 0, true, true},
+		{sarama.ErrNoError, 10, false, false},
+		// batched messages report the result of their batch
+		{sarama.ErrNotEnoughReplicas, 10, true, false},
+	}
+
+	for _, tt := range Tests {
+		broker := sarama.NewMockBroker(t, 1)
+		broker.SetHandlerByMap(map[string]sarama.MockResponse{
+			"MetadataRequest": sarama.NewMockMetadataResponse(t).
+				SetBroker(broker.Addr(), broker.BrokerID()).
+				SetLeader("kubewatch", 0, broker.BrokerID()),
+			// produce requests are v3 with Kafka 1.0.0
+			"ProduceRequest": sarama.NewMockProduceResponse(t).SetVersion(3).
+				SetError("kubewatch", 0, tt.kerr),
+		})
+
+		c := &config.Config{}
+		c.Handler.Kafka = config.Kafka{Brokers: []string{broker.Addr()}, Topic: "kubewatch", BatchSize: tt.batchSize, BatchTimeout: "10ms"}
+		s := &Kafka{}
+		if err := s.Init(c); err != nil {
+			t.Fatal(err)
+		}
+		s.conf.Producer.Retry.Max = 0
+		err := s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+		s.Close()
+		broker.Close()
+
+		if (err != nil) != tt.isErr {
+			t.Fatalf("%v: unexpected error %v", tt.kerr, err)
+		}
+		if got := delivery.IsPermanent(err); got != tt.permanent {
+			t.Errorf("%v: expected IsPermanent() to be %v, got %v", tt.kerr, tt.permanent, got)
+		}
+	}
+}
+
+func TestKafkaClose(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("kubewatch", 0, broker.BrokerID()),
+		"ProduceRequest": sarama.NewMockProduceResponse(t).SetVersion(3),
+	})
+
+	c := &config.Config{}
+	c.Handler.Kafka = config.Kafka{Brokers: []string{broker.Addr()}, Topic: "kubewatch", BatchSize: 10, BatchTimeout: "500ms"}
+	s := &Kafka{}
+	if err := s.Init(c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.connect(); err != nil {
+		t.Fatal(err)
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// the batch is neither full nor due, closing waits for it to be sent
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var produced bool
+	for _, rr := range broker.History() {
+		if _, ok := rr.Request.(*sarama.ProduceRequest); ok {
+			produced = true
+		}
+	}
+	if !produced {
+		t.Error("expected the batch sent before Close() returned")
+	}
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("expected the message delivered, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message not flushed by Close()")
+	}
+}
+
+func TestKafkaUnavailable(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	addr := broker.Addr()
+	broker.Close()
+
+	c := &config.Config{}
+	c.Handler.Kafka = config.Kafka{Brokers: []string{addr}, Topic: "kubewatch"}
+	s := &Kafka{}
+	if err := s.Init(c); err != nil {
+		t.Fatalf("expected Init() to succeed without brokers, got %v", err)
+	}
+	s.conf.Metadata.Retry.Max = 0
+	err := s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+	if err == nil || delivery.IsPermanent(err) {
+		t.Errorf("expected a transient error, got %v", err)
+	}
+}
@@ -27,7 +27,9 @@ import (
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
 )
 
 var mattermostColors = map[string]string{
@@ -56,6 +58,8 @@ type Mattermost struct {
 	Channel  string
 	Url      string
 	Username string
+
+	renderer *message.Renderer
 }
 
 // MattermostMessage struct for messages
@@ -95,20 +99,26 @@ func (m *Mattermost) Init(c *config.Config) error {
 	m.Url = url
 	m.Username = username
 
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	m.renderer = renderer
+
 	return checkMissingMattermostVars(m)
 }
 
 // Handle handles an event.
-func (m *Mattermost) Handle(e event.Event) {
+func (m *Mattermost) Handle(e event.Event) error {
 	mattermostMessage := prepareMattermostMessage(e, m)
 
 	err := postMessage(m.Url, mattermostMessage)
 	if err != nil {
-		log.Printf("%s\n", err)
-		return
+		return err
 	}
 
 	log.Printf("Message successfully sent to channel %s at %s", m.Channel, time.Now())
+	return nil
 }
 
 func checkMissingMattermostVars(s *Mattermost) error {
@@ -126,7 +136,7 @@ func prepareMattermostMessage(e event.Event, m *Mattermost) *MattermostMessage {
 		IconUrl:  "https://raw.githubusercontent.com/kubernetes/kubernetes/master/logo/logo_with_border.png",
 		Attachements: []MattermostMessageAttachement{
 			{
-				Title: e.Message(),
+				Title: m.renderer.Message(e),
 				Color: mattermostColors[e.Status],
 			},
 		},
@@ -136,20 +146,21 @@ func prepareMattermostMessage(e event.Event, m *Mattermost) *MattermostMessage {
 func postMessage(url string, mattermostMessage *MattermostMessage) error {
 	message, err := json.Marshal(mattermostMessage)
 	if err != nil {
-		return err
+		return delivery.Permanent(err)
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
 	if err != nil {
-		return err
+		return delivery.Permanent(err)
 	}
 	req.Header.Add("Content-Type", "application/json")
 
 	client := &http.Client{}
-	_, err = client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	return delivery.CheckResponse(resp)
 }
@@ -0,0 +1,219 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+const (
+	// DefaultTopic is the template of the topics by default.
+	DefaultTopic = "kubewatch/{{ .Cluster }}/{{ .Namespace }}/{{ .Kind }}/{{ .Reason }}"
+	// DefaultClientID prefixes the client ID by default, followed by a random
+	// suffix so that the processes sharing a configuration, e.g. kubewatch
+	// and kubewatch dlq replay, do not disconnect each other.
+	DefaultClientID = "kubewatch"
+	// DefaultWillTopic is the topic announcing whether kubewatch is connected by default.
+	DefaultWillTopic = "kubewatch/status"
+	// DefaultWillMessage is the last will message by default.
+	DefaultWillMessage = "offline"
+	// onlineMessage is published, retained, to the will topic once connected.
+	onlineMessage = "online"
+)
+
+// timeout is how long connecting, or publishing an event, waits for the broker.
+const timeout = 5 * time.Second
+
+var mqttErrMsg = `
+%s
+
+You need to set the MQTT url
+using "--url/-u" or using environment variables:
+
+export KW_MQTT_URL=mqtt_url
+
+Command line flags will override environment variables
+
+`
+
+// MQTT handler implements handler.Handler interface,
+// Publish events as JSON to MQTT topics
+type MQTT struct {
+	Url     string
+	QoS     byte
+	Retain  bool
+	Cluster string
+
+	renderer *message.Renderer
+	topic    *message.Topic
+	options  *paho.ClientOptions
+	mu       sync.Mutex
+	client   paho.Client
+}
+
+// Init prepares MQTT configuration
+func (m *MQTT) Init(c *config.Config) error {
+	url := c.Handler.MQTT.Url
+	topic := c.Handler.MQTT.Topic
+
+	if url == "" {
+		url = os.Getenv("KW_MQTT_URL")
+	}
+	if topic == "" {
+		topic = DefaultTopic
+	}
+
+	m.Url = url
+	m.QoS = byte(c.Handler.MQTT.QoS)
+	m.Retain = c.Handler.MQTT.Retain
+	m.Cluster = c.ClusterName
+
+	if err := checkMissingMQTTVars(m); err != nil {
+		return err
+	}
+
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	m.renderer = renderer
+	if c.Handler.MQTT.QoS < 0 || c.Handler.MQTT.QoS > 2 {
+		return fmt.Errorf("mqtt: qos: unknown value %d, expected 0, 1 or 2", c.Handler.MQTT.QoS)
+	}
+	if m.topic, err = message.NewTopic(topic, "/", "+#"); err != nil {
+		return fmt.Errorf("mqtt: topic: %v", err)
+	}
+	if m.options, err = options(c.Handler.MQTT); err != nil {
+		return fmt.Errorf("mqtt: %v", err)
+	}
+	return nil
+}
+
+// Handle handles an event.
+func (m *MQTT) Handle(e event.Event) error {
+	topic, err := m.topic.Render(e, m.Cluster)
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+	payload, err := json.Marshal(webhook.NewMessage(e, m.renderer.Message(e)))
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+	client, err := m.connect()
+	if err != nil {
+		return err
+	}
+
+	token := client.Publish(topic, m.QoS, m.Retain, payload)
+	if !token.WaitTimeout(timeout) {
+		return fmt.Errorf("publishing to %s: not acknowledged within %v", topic, timeout)
+	}
+	if err := token.Error(); err != nil {
+		return err
+	}
+
+	log.Printf("Message successfully published to %s at %s ", topic, time.Now())
+	return nil
+}
+
+func checkMissingMQTTVars(m *MQTT) error {
+	if m.Url == "" {
+		return fmt.Errorf(mqttErrMsg, "Missing MQTT url")
+	}
+
+	return nil
+}
+
+// options returns the options of the client, whose will topic announces
+// whether it is connected
+func options(c config.MQTT) (*paho.ClientOptions, error) {
+	clientID, willTopic, willMessage := c.ClientID, c.WillTopic, c.WillMessage
+	if clientID == "" {
+		clientID = DefaultClientID + "-" + rand.String(8)
+	}
+	if willTopic == "" {
+		willTopic = DefaultWillTopic
+	}
+	if willMessage == "" {
+		willMessage = DefaultWillMessage
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(c.Url).
+		SetClientID(clientID).
+		SetUsername(c.Username).
+		SetPassword(c.Password).
+		SetConnectTimeout(timeout).
+		SetAutoReconnect(true).
+		SetWill(willTopic, willMessage, byte(c.QoS), true).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			logrus.Warnf("mqtt: connection lost: %v", err)
+		}).
+		SetOnConnectHandler(func(client paho.Client) {
+			token := client.Publish(willTopic, byte(c.QoS), true, onlineMessage)
+			go func() {
+				if token.WaitTimeout(timeout) && token.Error() != nil {
+					logrus.Errorf("mqtt: publishing to %s: %v", willTopic, token.Error())
+				}
+			}()
+		})
+
+	tlsConfig, err := c.TLS.Config()
+	if err != nil {
+		return nil, fmt.Errorf("tls.%v", err)
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+	return opts, nil
+}
+
+// connect returns the client, connecting on first use so that kubewatch starts
+// while the broker is unavailable. The client then reconnects by itself.
+func (m *MQTT) connect() (paho.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.client != nil {
+		return m.client, nil
+	}
+	client := paho.NewClient(m.options)
+	token := client.Connect()
+	if !token.WaitTimeout(timeout) {
+		client.Disconnect(0)
+		return nil, fmt.Errorf("connecting to %s: timed out after %v", m.Url, timeout)
+	}
+	if err := token.Error(); err != nil {
+		client.Disconnect(0)
+		return nil, fmt.Errorf("connecting to %s: %v", m.Url, err)
+	}
+	m.client = client
+	return client, nil
+}
@@ -0,0 +1,334 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+)
+
+// publication is a message published to the broker
+type publication struct {
+	qos     byte
+	retain  bool
+	payload []byte
+}
+
+// session is what a client announced when connecting
+type session struct {
+	clientID, username, password string
+	willTopic, willMessage       string
+	willQoS                      byte
+	willRetain                   bool
+}
+
+// broker is a minimal MQTT 3.1.1 broker recording the sessions and the
+// published messages, and acknowledging them
+type broker struct {
+	listener net.Listener
+
+	mu        sync.Mutex
+	refuse    bool
+	sessions  []session
+	published map[string]publication
+}
+
+func newBroker(t *testing.T) *broker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &broker{listener: listener, published: map[string]publication{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *broker) url() string {
+	return "tcp://" + b.listener.Addr().String()
+}
+
+// message waits for a message published to a topic: publishing with QoS 0,
+// or without waiting, does not wait for the broker to read it
+func (b *broker) message(topic string) (publication, bool) {
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		b.mu.Lock()
+		p, ok := b.published[topic]
+		b.mu.Unlock()
+		if ok || time.Now().After(deadline) {
+			return p, ok
+		}
+	}
+}
+
+func (b *broker) connected() []session {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]session(nil), b.sessions...)
+}
+
+func (b *broker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		header, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return
+		}
+		packet := make([]byte, length)
+		if _, err := io.ReadFull(r, packet); err != nil {
+			return
+		}
+
+		switch header >> 4 {
+		case 1: // CONNECT
+			var s session
+			_, rest := str(packet) // protocol name, MQTT or MQIsdp
+			flags := rest[1]
+			rest = rest[4:] // level, flags, keep alive
+			s.clientID, rest = str(rest)
+			if flags&0x04 != 0 {
+				s.willTopic, rest = str(rest)
+				s.willMessage, rest = str(rest)
+				s.willQoS, s.willRetain = flags>>3&3, flags&0x20 != 0
+			}
+			if flags&0x80 != 0 {
+				s.username, rest = str(rest)
+			}
+			if flags&0x40 != 0 {
+				s.password, _ = str(rest)
+			}
+			b.mu.Lock()
+			b.sessions = append(b.sessions, s)
+			refuse := b.refuse
+			b.mu.Unlock()
+			if refuse {
+				conn.Write([]byte{0x20, 2, 0, 5}) // not authorized
+				return
+			}
+			conn.Write([]byte{0x20, 2, 0, 0})
+		case 3: // PUBLISH
+			qos := header >> 1 & 3
+			topic, rest := str(packet)
+			var id []byte
+			if qos > 0 {
+				id, rest = rest[:2], rest[2:]
+			}
+			b.mu.Lock()
+			b.published[topic] = publication{qos: qos, retain: header&1 != 0, payload: rest}
+			b.mu.Unlock()
+			switch qos {
+			case 1:
+				conn.Write([]byte{0x40, 2, id[0], id[1]}) // PUBACK
+			case 2:
+				conn.Write([]byte{0x50, 2, id[0], id[1]}) // PUBREC
+			}
+		case 6: // PUBREL
+			conn.Write([]byte{0x70, 2, packet[0], packet[1]}) // PUBCOMP
+		case 12: // PINGREQ
+			conn.Write([]byte{0xD0, 0})
+		case 14: // DISCONNECT
+			return
+		}
+	}
+}
+
+func str(b []byte) (string, []byte) {
+	n := int(binary.BigEndian.Uint16(b))
+	return string(b[2 : 2+n]), b[2+n:]
+}
+
+func TestMQTTInit(t *testing.T) {
+	s := &MQTT{}
+	expectedError := fmt.Errorf(mqttErrMsg, "Missing MQTT url")
+
+	var Tests = []struct {
+		mqtt config.MQTT
+		err  error
+	}{
+		{config.MQTT{Url: "tcp://localhost:1883"}, nil},
+		{config.MQTT{Url: "tcp://localhost:1883", QoS: 3}, fmt.Errorf("mqtt: qos: unknown value 3, expected 0, 1 or 2")},
+		{config.MQTT{}, expectedError},
+	}
+
+	for _, tt := range Tests {
+		c := &config.Config{}
+		c.Handler.MQTT = tt.mqtt
+		if err := s.Init(c); !reflect.DeepEqual(err, tt.err) {
+			t.Fatalf("Init(): %v", err)
+		}
+	}
+}
+
+func TestMQTTPublish(t *testing.T) {
+	e := event.Event{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Updated", Status: "Warning"}
+
+	var Tests = []struct {
+		mqtt  config.MQTT
+		topic string
+	}{
+		{config.MQTT{}, "kubewatch/prod/default/deployment/Updated"},
+		{config.MQTT{QoS: 1, Retain: true, Topic: "events/{{ .Kind }}/{{ .Name }}"}, "events/deployment/foo"},
+		{config.MQTT{QoS: 2}, "kubewatch/prod/default/deployment/Updated"},
+	}
+
+	for _, tt := range Tests {
+		b := newBroker(t)
+		c := &config.Config{ClusterName: "prod"}
+		c.Handler.MQTT = tt.mqtt
+		c.Handler.MQTT.Url = b.url()
+		s := &MQTT{}
+		if err := s.Init(c); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Handle(e); err != nil {
+			t.Fatalf("%s: %v", tt.topic, err)
+		}
+		p, ok := b.message(tt.topic)
+		s.client.Disconnect(100)
+		b.listener.Close()
+
+		if !ok {
+			t.Fatalf("%s: no message published", tt.topic)
+		}
+		if p.qos != byte(tt.mqtt.QoS) || p.retain != tt.mqtt.Retain {
+			t.Errorf("%s: published with qos %d and retain %v", tt.topic, p.qos, p.retain)
+		}
+		var msg webhook.WebhookMessage
+		if err := json.Unmarshal(p.payload, &msg); err != nil {
+			t.Fatalf("%s: %v", tt.topic, err)
+		}
+		if msg.EventMeta.Name != "foo" || msg.Text != e.Message() {
+			t.Errorf("%s: unexpected message %+v", tt.topic, msg)
+		}
+	}
+}
+
+func TestMQTTWill(t *testing.T) {
+	var Tests = []struct {
+		mqtt    config.MQTT
+		session session
+	}{
+		{
+			config.MQTT{},
+			session{clientID: "kubewatch", willTopic: "kubewatch/status", willMessage: "offline", willRetain: true},
+		},
+		{
+			config.MQTT{QoS: 1, ClientID: "kubewatch-edge", Username: "kubewatch", Password: "secret", WillTopic: "edge/kubewatch", WillMessage: "gone"},
+			session{clientID: "kubewatch-edge", username: "kubewatch", password: "secret", willTopic: "edge/kubewatch", willMessage: "gone", willQoS: 1, willRetain: true},
+		},
+	}
+
+	for _, tt := range Tests {
+		b := newBroker(t)
+		c := &config.Config{}
+		c.Handler.MQTT = tt.mqtt
+		c.Handler.MQTT.Url = b.url()
+		s := &MQTT{}
+		if err := s.Init(c); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"}); err != nil {
+			t.Fatal(err)
+		}
+
+		p, _ := b.message(tt.session.willTopic)
+		s.client.Disconnect(100)
+		b.listener.Close()
+
+		sessions := b.connected()
+		if len(sessions) == 1 && tt.mqtt.ClientID == "" {
+			// the default client ID has a random suffix
+			if id := sessions[0].clientID; strings.HasPrefix(id, "kubewatch-") && len(id) == len("kubewatch-")+8 {
+				sessions[0].clientID = tt.session.clientID
+			}
+		}
+		if len(sessions) != 1 || sessions[0] != tt.session {
+			t.Errorf("unexpected sessions %+v, expected %+v", sessions, tt.session)
+		}
+		if string(p.payload) != "online" || !p.retain {
+			t.Errorf("%s: expected the retained message online, got %q", tt.session.willTopic, p.payload)
+		}
+	}
+}
+
+func TestMQTTUnavailable(t *testing.T) {
+	b := newBroker(t)
+	b.listener.Close()
+
+	c := &config.Config{}
+	c.Handler.MQTT.Url = b.url()
+	s := &MQTT{}
+	if err := s.Init(c); err != nil {
+		t.Fatalf("expected Init() to succeed without broker, got %v", err)
+	}
+	err := s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+	if err == nil || delivery.IsPermanent(err) {
+		t.Errorf("expected a transient error, got %v", err)
+	}
+}
+
+func TestMQTTRefused(t *testing.T) {
+	b := newBroker(t)
+	defer b.listener.Close()
+	b.refuse = true
+
+	c := &config.Config{}
+	c.Handler.MQTT.Url = b.url()
+	s := &MQTT{}
+	if err := s.Init(c); err != nil {
+		t.Fatal(err)
+	}
+	err := s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+	if err == nil || delivery.IsPermanent(err) {
+		t.Errorf("expected a transient error, got %v", err)
+	}
+
+	// the next delivery connects again
+	b.mu.Lock()
+	b.refuse = false
+	b.mu.Unlock()
+	if err := s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"}); err != nil {
+		t.Fatal(err)
+	}
+	s.client.Disconnect(100)
+}
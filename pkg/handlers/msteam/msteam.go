@@ -20,13 +20,14 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
 )
 
 var msteamsErrMsg = `
@@ -84,29 +85,24 @@ type TeamsMessageCardSectionFacts struct {
 type MSTeams struct {
 	// TeamsWebhookURL is the webhook url of the Teams connector
 	TeamsWebhookURL string
+
+	renderer *message.Renderer
 }
 
 // sendCard sends the JSON Encoded TeamsMessageCard to the webhook URL
 func sendCard(ms *MSTeams, card *TeamsMessageCard) (*http.Response, error) {
 	buffer := new(bytes.Buffer)
 	if err := json.NewEncoder(buffer).Encode(card); err != nil {
-		return nil, fmt.Errorf("Failed encoding message card: %v", err)
+		return nil, delivery.Permanent(fmt.Errorf("Failed encoding message card: %v", err))
 	}
 	res, err := http.Post(ms.TeamsWebhookURL, "application/json", buffer)
 	if err != nil {
 		return nil, fmt.Errorf("Failed sending to webhook url %s. Got the error: %v",
 			ms.TeamsWebhookURL, err)
 	}
-	if res.StatusCode != http.StatusOK {
-		resMessage, err := ioutil.ReadAll(res.Body)
-		if err != nil {
-			return nil, fmt.Errorf("Failed reading Teams http response: %v", err)
-		}
-		return nil, fmt.Errorf("Failed sending to the Teams Channel. Teams http response: %s, %s",
-			res.Status, string(resMessage))
-	}
-	if err := res.Body.Close(); err != nil {
-		return nil, err
+	defer res.Body.Close()
+	if err := delivery.CheckResponse(res); err != nil {
+		return nil, fmt.Errorf("Failed sending to the Teams Channel: %w", err)
 	}
 	return res, nil
 }
@@ -123,12 +119,18 @@ func (ms *MSTeams) Init(c *config.Config) error {
 		return fmt.Errorf(msteamsErrMsg, "Missing MS teams webhook URL")
 	}
 
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	ms.renderer = renderer
+
 	ms.TeamsWebhookURL = webhookURL
 	return nil
 }
 
 // Handle handles notification.
-func (ms *MSTeams) Handle(e event.Event) {
+func (ms *MSTeams) Handle(e event.Event) error {
 	card := &TeamsMessageCard{
 		Type:    messageType,
 		Context: context,
@@ -140,14 +142,20 @@ func (ms *MSTeams) Handle(e event.Event) {
 	card.ThemeColor = msTeamsColors[e.Status]
 
 	var s TeamsMessageCardSection
-	s.ActivityTitle = e.Message()
+	s.ActivityTitle = ms.renderer.Message(e)
 	s.Markdown = true
+	for _, c := range e.Diff {
+		s.Facts = append(s.Facts, TeamsMessageCardSectionFacts{
+			Name:  c.Path,
+			Value: fmt.Sprintf("`%s` → `%s`", c.OldValue(), c.NewValue()),
+		})
+	}
 	card.Sections = append(card.Sections, s)
 
 	if _, err := sendCard(ms, card); err != nil {
-		log.Printf("%s\n", err)
-		return
+		return err
 	}
 
 	log.Printf("Message successfully sent to MS Teams")
+	return nil
 }
@@ -176,3 +176,54 @@ func TestObjectUpdated(t *testing.T) {
 
 	ms.Handle(oldP)
 }
+
+// Tests that the changes of an update are rendered as facts
+func TestObjectUpdatedWithDiff(t *testing.T) {
+	expectedCard := TeamsMessageCard{
+		Type:       messageType,
+		Context:    context,
+		ThemeColor: msTeamsColors["Warning"],
+		Summary:    "kubewatch notification received",
+		Title:      "kubewatch",
+		Text:       "",
+		Sections: []TeamsMessageCardSection{
+			{
+				ActivityTitle: "A `deployment` in namespace `new` has been `Updated`:\n`foo`",
+				Facts: []TeamsMessageCardSectionFacts{
+					{
+						Name:  "spec.template.spec.containers[0].image",
+						Value: "`\"nginx:1.18\"` → `\"nginx:1.19\"`",
+					},
+				},
+				Markdown: true,
+			},
+		},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		decoder := json.NewDecoder(r.Body)
+		var c TeamsMessageCard
+		if err := decoder.Decode(&c); err != nil {
+			t.Errorf("%v", err)
+		}
+		if !reflect.DeepEqual(c, expectedCard) {
+			t.Errorf("expected %v, got %v", expectedCard, c)
+		}
+	}))
+
+	ms := &MSTeams{TeamsWebhookURL: ts.URL}
+
+	p := event.Event{
+		Name:      "foo",
+		Namespace: "new",
+		Kind:      "deployment",
+		Reason:    "Updated",
+		Status:    "Warning",
+		Diff: []event.Change{
+			{Path: "spec.template.spec.containers[0].image", Old: "nginx:1.18", New: "nginx:1.19"},
+		},
+	}
+
+	ms.Handle(p)
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nats
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSubject is the template of the subjects by default.
+const DefaultSubject = "kubewatch.{{ .Cluster }}.{{ .Namespace }}.{{ .Kind }}.{{ .Reason }}"
+
+// timeout is how long publishing an event waits for the server, or JetStream, to acknowledge it.
+const timeout = 5 * time.Second
+
+var natsErrMsg = `
+%s
+
+You need to set the NATS url
+using "--url/-u" or using environment variables:
+
+export KW_NATS_URL=nats_url
+
+Command line flags will override environment variables
+
+`
+
+// NATS handler implements handler.Handler interface,
+// Publish events as JSON to NATS subjects, or to JetStream
+type NATS struct {
+	Url       string
+	JetStream bool
+	Cluster   string
+
+	renderer *message.Renderer
+	subject  *message.Topic
+	options  []nats.Option
+	mu       sync.Mutex
+	conn     *nats.Conn
+	js       nats.JetStreamContext
+}
+
+// Init prepares NATS configuration
+func (n *NATS) Init(c *config.Config) error {
+	url := c.Handler.NATS.Url
+	subject := c.Handler.NATS.Subject
+
+	if url == "" {
+		url = os.Getenv("KW_NATS_URL")
+	}
+	if subject == "" {
+		subject = DefaultSubject
+	}
+
+	n.Url = url
+	n.JetStream = c.Handler.NATS.JetStream
+	n.Cluster = c.ClusterName
+
+	if err := checkMissingNATSVars(n); err != nil {
+		return err
+	}
+
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	n.renderer = renderer
+	if n.subject, err = message.NewTopic(subject, ".", "*>"); err != nil {
+		return fmt.Errorf("nats: subject: %v", err)
+	}
+	if n.options, err = options(c.Handler.NATS); err != nil {
+		return fmt.Errorf("nats: %v", err)
+	}
+	return nil
+}
+
+// Handle handles an event.
+func (n *NATS) Handle(e event.Event) error {
+	subject, err := n.subject.Render(e, n.Cluster)
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+	data, err := json.Marshal(webhook.NewMessage(e, n.renderer.Message(e)))
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+	conn, js, err := n.connect()
+	if err != nil {
+		return err
+	}
+
+	if js != nil {
+		if _, err := js.Publish(subject, data, nats.AckWait(timeout)); err != nil {
+			return err
+		}
+	} else {
+		if err := conn.Publish(subject, data); err != nil {
+			return err
+		}
+		if err := conn.FlushTimeout(timeout); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Message successfully published to %s at %s ", subject, time.Now())
+	return nil
+}
+
+func checkMissingNATSVars(n *NATS) error {
+	if n.Url == "" {
+		return fmt.Errorf(natsErrMsg, "Missing NATS url")
+	}
+
+	return nil
+}
+
+// options returns the options of the connection
+func options(c config.NATS) ([]nats.Option, error) {
+	opts := []nats.Option{
+		nats.Name("kubewatch"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logrus.Warnf("nats: disconnected: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			logrus.Infof("nats: reconnected to %s", conn.ConnectedUrl())
+		}),
+	}
+	if c.Username != "" {
+		opts = append(opts, nats.UserInfo(c.Username, c.Password))
+	}
+	if c.Token != "" {
+		opts = append(opts, nats.Token(c.Token))
+	}
+	if c.Credentials != "" {
+		opts = append(opts, nats.UserCredentials(c.Credentials))
+	}
+	tlsConfig, err := c.TLS.Config()
+	if err != nil {
+		return nil, fmt.Errorf("tls.%v", err)
+	}
+	if tlsConfig != nil {
+		opts = append(opts, nats.Secure(tlsConfig))
+	}
+	return opts, nil
+}
+
+// connect returns the connection, and the JetStream context if enabled,
+// connecting on first use so that kubewatch starts while NATS is unavailable.
+// The connection then reconnects by itself.
+func (n *NATS) connect() (*nats.Conn, nats.JetStreamContext, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.conn == nil {
+		conn, err := nats.Connect(n.Url, n.options...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("connecting to %s: %v", n.Url, err)
+		}
+		n.conn = conn
+	}
+	if n.JetStream && n.js == nil {
+		js, err := n.conn.JetStream(nats.MaxWait(timeout))
+		if err != nil {
+			return nil, nil, fmt.Errorf("jetstream: %v", err)
+		}
+		n.js = js
+	}
+	return n.conn, n.js, nil
+}
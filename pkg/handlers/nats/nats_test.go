@@ -0,0 +1,204 @@
+/*
+Copyright 2018 Bitnami
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nats
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+)
+
+// server is a minimal NATS server recording the published messages, and
+// acknowledging them like JetStream when they expect a reply
+type server struct {
+	listener net.Listener
+
+	mu        sync.Mutex
+	published map[string][]byte
+}
+
+func newServer(t *testing.T) *server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &server{listener: listener, published: map[string][]byte{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *server) url() string {
+	return "nats://" + s.listener.Addr().String()
+}
+
+func (s *server) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"version\":\"2.2.0\",\"proto\":1,\"headers\":true,\"max_payload\":1048576}\r\n")
+
+	r := bufio.NewReader(conn)
+	subs := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch op := strings.ToUpper(fields[0]); op {
+		case "PING":
+			fmt.Fprintf(conn, "PONG\r\n")
+		case "SUB":
+			subs[fields[1]] = fields[len(fields)-1]
+		case "PUB", "HPUB":
+			// PUB <subject> [reply] <size>, HPUB <subject> [reply] <header size> <size>
+			args := fields[1:]
+			headers := 0
+			if op == "HPUB" {
+				headers, _ = strconv.Atoi(args[len(args)-2])
+				args = append(args[:len(args)-2], args[len(args)-1])
+			}
+			size, _ := strconv.Atoi(args[len(args)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			var reply string
+			if len(args) == 3 {
+				reply = args[1]
+			}
+			if response := s.publish(args[0], payload[headers:size]); reply != "" {
+				for sub, sid := range subs {
+					if strings.HasSuffix(sub, ".*") && strings.HasPrefix(reply, strings.TrimSuffix(sub, "*")) {
+						fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", reply, sid, len(response), response)
+					}
+				}
+			}
+		}
+	}
+}
+
+// publish records a message and returns the response of JetStream
+func (s *server) publish(subject string, data []byte) string {
+	if strings.HasPrefix(subject, "$JS.API.") {
+		return `{"type":"io.nats.jetstream.api.v1.account_info_response"}`
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.published[subject] = data
+	return `{"stream":"KUBEWATCH","seq":1}`
+}
+
+func (s *server) message(subject string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.published[subject]
+}
+
+func TestNATSInit(t *testing.T) {
+	s := &NATS{}
+	expectedError := fmt.Errorf(natsErrMsg, "Missing NATS url")
+
+	var Tests = []struct {
+		nats config.NATS
+		err  error
+	}{
+		{config.NATS{Url: "nats://localhost:4222"}, nil},
+		{config.NATS{}, expectedError},
+	}
+
+	for _, tt := range Tests {
+		c := &config.Config{}
+		c.Handler.NATS = tt.nats
+		if err := s.Init(c); !reflect.DeepEqual(err, tt.err) {
+			t.Fatalf("Init(): %v", err)
+		}
+	}
+}
+
+func TestNATSPublish(t *testing.T) {
+	e := event.Event{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Updated", Status: "Warning"}
+
+	var Tests = []struct {
+		nats    config.NATS
+		subject string
+	}{
+		{config.NATS{}, "kubewatch.prod.default.deployment.Updated"},
+		{config.NATS{JetStream: true, Subject: "events.{{ .Kind }}.{{ .Name }}"}, "events.deployment.foo"},
+	}
+
+	for _, tt := range Tests {
+		srv := newServer(t)
+		c := &config.Config{ClusterName: "prod"}
+		c.Handler.NATS = tt.nats
+		c.Handler.NATS.Url = srv.url()
+		s := &NATS{}
+		if err := s.Init(c); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Handle(e); err != nil {
+			t.Fatalf("%s: %v", tt.subject, err)
+		}
+		s.conn.Close()
+		srv.listener.Close()
+
+		var msg webhook.WebhookMessage
+		if err := json.Unmarshal(srv.message(tt.subject), &msg); err != nil {
+			t.Fatalf("%s: %v", tt.subject, err)
+		}
+		if msg.EventMeta.Name != "foo" || msg.Text != e.Message() {
+			t.Errorf("%s: unexpected message %+v", tt.subject, msg)
+		}
+	}
+}
+
+func TestNATSUnavailable(t *testing.T) {
+	srv := newServer(t)
+	srv.listener.Close()
+
+	c := &config.Config{}
+	c.Handler.NATS.Url = srv.url()
+	s := &NATS{}
+	if err := s.Init(c); err != nil {
+		t.Fatalf("expected Init() to succeed without server, got %v", err)
+	}
+	err := s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+	if err == nil || delivery.IsPermanent(err) {
+		t.Errorf("expected a transient error, got %v", err)
+	}
+}
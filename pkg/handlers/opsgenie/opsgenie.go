@@ -0,0 +1,268 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsgenie
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultURL is the URL of the Opsgenie API.
+const DefaultURL = "https://api.opsgenie.com"
+
+// Length limits of the fields of an alert.
+const (
+	maxMessage     = 130
+	maxDescription = 15000
+	maxTags        = 20
+	maxTag         = 50
+)
+
+var opsgenieErrMsg = `
+%s
+
+You need to set the Opsgenie API key
+using "--apikey/-k" or using environment variables:
+
+export KW_OPSGENIE_API_KEY=opsgenie_api_key
+
+Command line flags will override environment variables
+
+`
+
+// priorities maps the status of events to Opsgenie priorities.
+var priorities = map[string]string{
+	"Normal":  "P5",
+	"Warning": "P3",
+	"Danger":  "P1",
+}
+
+// Opsgenie handler implements handler.Handler interface,
+// Notify event to Opsgenie as alerts, closing them on recovery
+type Opsgenie struct {
+	ApiKey  string
+	Url     string
+	Cluster string
+
+	renderer *message.Renderer
+}
+
+// Alert is an alert created through the Opsgenie Alerts API.
+type Alert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Source      string            `json:"source"`
+	Priority    string            `json:"priority"`
+}
+
+// Close is the request closing an alert.
+type Close struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+// Init prepares Opsgenie configuration
+func (o *Opsgenie) Init(c *config.Config) error {
+	apiKey := c.Handler.Opsgenie.ApiKey
+	url := c.Handler.Opsgenie.Url
+
+	if apiKey == "" {
+		apiKey = os.Getenv("KW_OPSGENIE_API_KEY")
+	}
+	if url == "" {
+		url = DefaultURL
+	}
+
+	o.ApiKey = apiKey
+	o.Url = strings.TrimSuffix(url, "/")
+	o.Cluster = c.ClusterName
+
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	o.renderer = renderer
+
+	return checkMissingOpsgenieVars(o)
+}
+
+// Handle handles an event.
+func (o *Opsgenie) Handle(e event.Event) error {
+	alias := alertAlias(o.Cluster, e)
+
+	if e.Recovered() {
+		path := "/v2/alerts/" + url.PathEscape(alias) + "/close?identifierType=alias"
+		if err := o.post(path, &Close{Source: "kubewatch", Note: o.renderer.Message(e)}); err != nil {
+			return err
+		}
+		log.Printf("Alert %s successfully closed in Opsgenie at %s ", alias, time.Now())
+		return nil
+	}
+	if !isCondition(e) {
+		logrus.Debugf("opsgenie: skipping %s event for %s, not a condition", e.Reason, e.Name)
+		return nil
+	}
+
+	if err := o.post("/v2/alerts", prepareOpsgenieAlert(e, o)); err != nil {
+		return err
+	}
+	log.Printf("Alert %s successfully sent to Opsgenie at %s ", alias, time.Now())
+	return nil
+}
+
+func checkMissingOpsgenieVars(o *Opsgenie) error {
+	if o.ApiKey == "" {
+		return fmt.Errorf(opsgenieErrMsg, "Missing Opsgenie API key")
+	}
+
+	return nil
+}
+
+// alertAlias identifies the alerts about the subject of an event, so that
+// Opsgenie deduplicates them and the recovery of the subject closes them.
+func alertAlias(cluster string, e event.Event) string {
+	kind, namespace, name := e.Subject()
+	var parts []string
+	for _, part := range []string{cluster, kind, namespace, name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// isCondition reports whether an event reports a condition worth an alert:
+// a k8s Event of Warning or Danger status about its object, e.g. NodeNotReady
+// or a pod in CrashLoopBackOff, rather than an object change or the expiry
+// of the k8s Event.
+func isCondition(e event.Event) bool {
+	return e.InvolvedObject != nil && !e.Expired() && (e.Status == "Warning" || e.Status == "Danger")
+}
+
+// alertTags returns the tags of an alert: the kind and namespace of the
+// subject of the event, the cluster, and the labels as key:value.
+func alertTags(cluster string, e event.Event) []string {
+	kind, namespace, _ := e.Subject()
+	var tags []string
+	for _, tag := range []string{cluster, kind, namespace} {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	labels := make([]string, 0, len(e.Labels))
+	for k, v := range e.Labels {
+		labels = append(labels, k+":"+v)
+	}
+	sort.Strings(labels)
+	tags = append(tags, labels...)
+
+	if len(tags) > maxTags {
+		tags = tags[:maxTags]
+	}
+	for i, tag := range tags {
+		tags[i] = truncate(tag, maxTag)
+	}
+	return tags
+}
+
+func prepareOpsgenieAlert(e event.Event, o *Opsgenie) *Alert {
+	priority, ok := priorities[e.Status]
+	if !ok {
+		priority = "P3"
+	}
+	text := o.renderer.Message(e)
+	description := text
+	if diff := e.DiffMessage(); diff != "" {
+		description += "\n\n" + diff
+	}
+	details := map[string]string{
+		"kind":   e.Kind,
+		"name":   e.Name,
+		"reason": e.Reason,
+		"status": e.Status,
+	}
+	if e.Namespace != "" {
+		details["namespace"] = e.Namespace
+	}
+	if o.Cluster != "" {
+		details["cluster"] = o.Cluster
+	}
+	_, _, entity := e.Subject()
+
+	return &Alert{
+		Message:     truncate(strings.SplitN(text, "\n", 2)[0], maxMessage),
+		Alias:       alertAlias(o.Cluster, e),
+		Description: truncate(description, maxDescription),
+		Tags:        alertTags(o.Cluster, e),
+		Details:     details,
+		Entity:      entity,
+		Source:      "kubewatch",
+		Priority:    priority,
+	}
+}
+
+// truncate shortens s to at most n bytes, without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
+
+func (o *Opsgenie) post(path string, body interface{}) error {
+	message, err := json.Marshal(body)
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+
+	req, err := http.NewRequest("POST", o.Url+path, bytes.NewBuffer(message))
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Authorization", "GenieKey "+o.ApiKey)
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return delivery.CheckResponse(resp)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2018 Bitnami
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opsgenie
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+func TestOpsgenieInit(t *testing.T) {
+	s := &Opsgenie{}
+	expectedError := fmt.Errorf(opsgenieErrMsg, "Missing Opsgenie API key")
+
+	var Tests = []struct {
+		opsgenie config.Opsgenie
+		err      error
+	}{
+		{config.Opsgenie{ApiKey: "foo"}, nil},
+		{config.Opsgenie{}, expectedError},
+	}
+
+	for _, tt := range Tests {
+		c := &config.Config{}
+		c.Handler.Opsgenie = tt.opsgenie
+		if err := s.Init(c); !reflect.DeepEqual(err, tt.err) {
+			t.Fatalf("Init(): %v", err)
+		}
+		if tt.err == nil && s.Url != DefaultURL {
+			t.Errorf("expected the default URL, got %s", s.Url)
+		}
+	}
+}
+
+func TestOpsgenieHandle(t *testing.T) {
+	var Tests = []struct {
+		status    int
+		isErr     bool
+		permanent bool
+	}{
+		{http.StatusAccepted, false, false},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusServiceUnavailable, true, false},
+		{http.StatusUnauthorized, true, true},
+	}
+
+	for _, tt := range Tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+
+		s := &Opsgenie{ApiKey: "foo", Url: ts.URL}
+		pod := api_v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "foo-1"}
+		err := s.Handle(event.Event{Name: "foo-1.16a7b3c2d1e0f", Namespace: "default", Kind: "Backoff", Reason: "Created", Status: "Danger", InvolvedObject: &pod})
+		ts.Close()
+
+		if (err != nil) != tt.isErr {
+			t.Fatalf("%d: unexpected error %v", tt.status, err)
+		}
+		if got := delivery.IsPermanent(err); got != tt.permanent {
+			t.Errorf("%d: expected IsPermanent() to be %v, got %v", tt.status, tt.permanent, got)
+		}
+	}
+}
+
+func TestOpsgenieAlerts(t *testing.T) {
+	type request struct {
+		auth, uri string
+		alert     Alert
+	}
+	var got []request
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := request{auth: r.Header.Get("Authorization"), uri: r.URL.RequestURI()}
+		if err := json.NewDecoder(r.Body).Decode(&req.alert); err != nil {
+			t.Error(err)
+		}
+		got = append(got, req)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	node := api_v1.ObjectReference{Kind: "Node", Name: "node-1"}
+	pod := api_v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "foo-1"}
+	events := []event.Event{
+		{Name: "node-1.16a7b3c2d1e0f", Namespace: "default", Kind: "NodeNotReady", Reason: "Created", Status: "Danger", InvolvedObject: &node},
+		{Name: "node-1.16a7b3c2d1e10", Namespace: "default", Kind: "NodeReady", Reason: "Created", Status: "Normal", InvolvedObject: &node},
+		{Name: "foo-1.16a7b3c2d1e11", Namespace: "default", Kind: "Backoff", Reason: "Updated", Status: "Warning", InvolvedObject: &pod, Labels: map[string]string{"team": "payments", "app": "foo"}},
+		// object changes are not conditions
+		{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Created", Status: "Normal"},
+		{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Updated", Status: "Warning"},
+		{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Deleted", Status: "Danger"},
+		// nor are the expiries of k8s Events
+		{Name: "node-1.16a7b3c2d1e0f", Namespace: "default", Kind: "NodeNotReady", Reason: "Deleted", Status: "Danger", InvolvedObject: &node},
+		{Name: "node-1.16a7b3c2d1e10", Namespace: "default", Kind: "NodeReady", Reason: "Deleted", Status: "Danger", InvolvedObject: &node},
+	}
+	s := &Opsgenie{ApiKey: "key", Url: ts.URL, Cluster: "prod"}
+	for _, e := range events {
+		if err := s.Handle(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var Tests = []struct {
+		uri, alias, priority string
+		tags                 []string
+	}{
+		{"/v2/alerts", "prod/node/node-1", "P1", []string{"prod", "node"}},
+		{"/v2/alerts/prod%2Fnode%2Fnode-1/close?identifierType=alias", "", "", nil},
+		{"/v2/alerts", "prod/pod/default/foo-1", "P3", []string{"prod", "pod", "default", "app:foo", "team:payments"}},
+	}
+	if len(got) != len(Tests) {
+		t.Fatalf("expected %d requests, got %d", len(Tests), len(got))
+	}
+	for i, tt := range Tests {
+		req := got[i]
+		if req.auth != "GenieKey key" || req.uri != tt.uri {
+			t.Errorf("%d: expected a request to %s, got %s with %q", i, tt.uri, req.uri, req.auth)
+		}
+		if req.alert.Alias != tt.alias || req.alert.Priority != tt.priority || !reflect.DeepEqual(req.alert.Tags, tt.tags) {
+			t.Errorf("%d: unexpected alert %+v", i, req.alert)
+		}
+	}
+}
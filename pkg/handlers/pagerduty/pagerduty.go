@@ -0,0 +1,253 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pagerduty
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultURL is the URL of the PagerDuty Events API v2.
+const DefaultURL = "https://events.pagerduty.com/v2/enqueue"
+
+// maxSummary is the length limit of the summary of an alert.
+const maxSummary = 1024
+
+var pagerdutyErrMsg = `
+%s
+
+You need to set the PagerDuty routing key
+using "--routingkey/-k" or using environment variables:
+
+export KW_PAGERDUTY_ROUTING_KEY=pagerduty_routing_key
+
+Command line flags will override environment variables
+
+`
+
+// severities maps the status of events to PagerDuty severities.
+var severities = map[string]string{
+	"Normal":  "info",
+	"Warning": "warning",
+	"Danger":  "critical",
+}
+
+// PagerDuty handler implements handler.Handler interface,
+// Notify the conditions events report to PagerDuty as alerts, resolving them on recovery
+type PagerDuty struct {
+	RoutingKey string
+	Url        string
+	Cluster    string
+
+	renderer *message.Renderer
+}
+
+// Event is a PagerDuty Events API v2 event.
+type Event struct {
+	RoutingKey  string   `json:"routing_key"`
+	EventAction string   `json:"event_action"`
+	DedupKey    string   `json:"dedup_key"`
+	Payload     *Payload `json:"payload,omitempty"`
+}
+
+// Payload describes the alert triggered by an event.
+type Payload struct {
+	Summary       string        `json:"summary"`
+	Source        string        `json:"source"`
+	Severity      string        `json:"severity"`
+	Timestamp     string        `json:"timestamp,omitempty"`
+	Component     string        `json:"component,omitempty"`
+	Group         string        `json:"group,omitempty"`
+	Class         string        `json:"class,omitempty"`
+	CustomDetails CustomDetails `json:"custom_details"`
+}
+
+// CustomDetails holds the metadata of the object of an alert.
+type CustomDetails struct {
+	Cluster     string            `json:"cluster,omitempty"`
+	Kind        string            `json:"kind"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Name        string            `json:"name"`
+	Reason      string            `json:"reason"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Diff        string            `json:"diff,omitempty"`
+}
+
+// Init prepares PagerDuty configuration
+func (p *PagerDuty) Init(c *config.Config) error {
+	routingKey := c.Handler.PagerDuty.RoutingKey
+	url := c.Handler.PagerDuty.Url
+
+	if routingKey == "" {
+		routingKey = os.Getenv("KW_PAGERDUTY_ROUTING_KEY")
+	}
+	if url == "" {
+		url = DefaultURL
+	}
+
+	p.RoutingKey = routingKey
+	p.Url = url
+	p.Cluster = c.ClusterName
+
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	p.renderer = renderer
+
+	return checkMissingPagerDutyVars(p)
+}
+
+// Handle handles an event.
+func (p *PagerDuty) Handle(e event.Event) error {
+	pdEvent := preparePagerDutyEvent(e, p)
+	if pdEvent == nil {
+		logrus.Debugf("pagerduty: skipping %s event for %s, not a condition", e.Reason, e.Name)
+		return nil
+	}
+
+	err := postEvent(p.Url, pdEvent)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Event %s successfully sent to PagerDuty at %s ", pdEvent.DedupKey, time.Now())
+	return nil
+}
+
+func checkMissingPagerDutyVars(p *PagerDuty) error {
+	if p.RoutingKey == "" {
+		return fmt.Errorf(pagerdutyErrMsg, "Missing PagerDuty routing key")
+	}
+
+	return nil
+}
+
+// dedupKey identifies the alerts about the subject of an event, so that
+// PagerDuty groups them and the recovery of the subject resolves them.
+// Empty parts, e.g. the cluster name when unset, are left out.
+func dedupKey(cluster string, e event.Event) string {
+	kind, namespace, name := e.Subject()
+	var parts []string
+	for _, part := range []string{cluster, kind, namespace, name} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// isCondition reports whether an event reports a condition worth paging on:
+// a k8s Event of Warning or Danger status about its object, e.g. NodeNotReady
+// or a pod in CrashLoopBackOff, rather than an object change or the expiry
+// of the k8s Event.
+func isCondition(e event.Event) bool {
+	return e.InvolvedObject != nil && !e.Expired() && (e.Status == "Warning" || e.Status == "Danger")
+}
+
+// preparePagerDutyEvent returns the PagerDuty event of an event: a trigger for
+// a condition, a resolve for its recovery, or nil for the other events
+func preparePagerDutyEvent(e event.Event, p *PagerDuty) *Event {
+	pdEvent := &Event{
+		RoutingKey: p.RoutingKey,
+		DedupKey:   dedupKey(p.Cluster, e),
+	}
+	if e.Recovered() {
+		pdEvent.EventAction = "resolve"
+		return pdEvent
+	}
+	if !isCondition(e) {
+		return nil
+	}
+	pdEvent.EventAction = "trigger"
+
+	severity, ok := severities[e.Status]
+	if !ok {
+		severity = "info"
+	}
+	source := e.Host
+	if source == "" {
+		source = p.Cluster
+	}
+	if source == "" {
+		source = "kubewatch"
+	}
+	summary := p.renderer.Message(e)
+	if len(summary) > maxSummary {
+		summary = summary[:maxSummary]
+	}
+	var timestamp string
+	if !e.Timestamp.IsZero() {
+		timestamp = e.Timestamp.Format(time.RFC3339)
+	}
+
+	pdEvent.Payload = &Payload{
+		Summary:   summary,
+		Source:    source,
+		Severity:  severity,
+		Timestamp: timestamp,
+		Component: e.Kind,
+		Group:     e.Namespace,
+		Class:     e.Reason,
+		CustomDetails: CustomDetails{
+			Cluster:     p.Cluster,
+			Kind:        e.Kind,
+			Namespace:   e.Namespace,
+			Name:        e.Name,
+			Reason:      e.Reason,
+			Labels:      e.Labels,
+			Annotations: e.Annotations,
+			Diff:        e.DiffMessage(),
+		},
+	}
+	return pdEvent
+}
+
+func postEvent(url string, pdEvent *Event) error {
+	message, err := json.Marshal(pdEvent)
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return delivery.CheckResponse(resp)
+}
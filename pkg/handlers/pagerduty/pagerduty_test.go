@@ -0,0 +1,167 @@
+/*
+Copyright 2018 Bitnami
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pagerduty
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	api_v1 "k8s.io/api/core/v1"
+)
+
+func TestPagerDutyInit(t *testing.T) {
+	s := &PagerDuty{}
+	expectedError := fmt.Errorf(pagerdutyErrMsg, "Missing PagerDuty routing key")
+
+	var Tests = []struct {
+		pagerduty config.PagerDuty
+		err       error
+	}{
+		{config.PagerDuty{RoutingKey: "foo"}, nil},
+		{config.PagerDuty{}, expectedError},
+	}
+
+	for _, tt := range Tests {
+		c := &config.Config{}
+		c.Handler.PagerDuty = tt.pagerduty
+		if err := s.Init(c); !reflect.DeepEqual(err, tt.err) {
+			t.Fatalf("Init(): %v", err)
+		}
+		if tt.err == nil && s.Url != DefaultURL {
+			t.Errorf("expected the default URL, got %s", s.Url)
+		}
+	}
+}
+
+func TestPagerDutyHandle(t *testing.T) {
+	var Tests = []struct {
+		status    int
+		isErr     bool
+		permanent bool
+	}{
+		{http.StatusAccepted, false, false},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusInternalServerError, true, false},
+		{http.StatusBadRequest, true, true},
+	}
+
+	for _, tt := range Tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+
+		pod := api_v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "foo"}
+		s := &PagerDuty{RoutingKey: "foo", Url: ts.URL}
+		err := s.Handle(event.Event{Name: "foo.16a7b3c2d1e0f", Namespace: "default", Kind: "Backoff", Reason: "Created", Status: "Danger", InvolvedObject: &pod})
+		ts.Close()
+
+		if (err != nil) != tt.isErr {
+			t.Fatalf("%d: unexpected error %v", tt.status, err)
+		}
+		if got := delivery.IsPermanent(err); got != tt.permanent {
+			t.Errorf("%d: expected IsPermanent() to be %v, got %v", tt.status, tt.permanent, got)
+		}
+	}
+}
+
+func TestPagerDutyEvents(t *testing.T) {
+	var got []Event
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Error(err)
+		}
+		got = append(got, e)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	node := api_v1.ObjectReference{Kind: "Node", Name: "node-1"}
+	pod := api_v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "foo-1"}
+	events := []event.Event{
+		{Name: "node-1.16a7b3c2d1e0f", Namespace: "default", Kind: "NodeNotReady", Reason: "Created", Status: "Danger", InvolvedObject: &node},
+		{Name: "node-1.16a7b3c2d1e10", Namespace: "default", Kind: "NodeReady", Reason: "Created", Status: "Normal", InvolvedObject: &node},
+		{Name: "foo-1.16a7b3c2d1e11", Namespace: "default", Kind: "Backoff", Reason: "Updated", Status: "Warning", InvolvedObject: &pod},
+		// object changes are not conditions
+		{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Created", Status: "Normal"},
+		{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Updated", Status: "Warning"},
+		{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Deleted", Status: "Danger"},
+		// nor are the expiries of k8s Events
+		{Name: "node-1.16a7b3c2d1e0f", Namespace: "default", Kind: "NodeNotReady", Reason: "Deleted", Status: "Danger", InvolvedObject: &node},
+		{Name: "node-1.16a7b3c2d1e10", Namespace: "default", Kind: "NodeReady", Reason: "Deleted", Status: "Danger", InvolvedObject: &node},
+		{Name: "foo-1.16a7b3c2d1e11", Namespace: "default", Kind: "Backoff", Reason: "Deleted", Status: "Danger", InvolvedObject: &pod},
+	}
+	s := &PagerDuty{RoutingKey: "key", Url: ts.URL, Cluster: "prod"}
+	for _, e := range events {
+		if err := s.Handle(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var Tests = []struct {
+		action, dedupKey, severity string
+	}{
+		{"trigger", "prod/node/node-1", "critical"},
+		{"resolve", "prod/node/node-1", ""},
+		{"trigger", "prod/pod/default/foo-1", "warning"},
+	}
+	if len(got) != len(Tests) {
+		t.Fatalf("expected %d events, got %d", len(Tests), len(got))
+	}
+	for i, tt := range Tests {
+		e := got[i]
+		if e.RoutingKey != "key" || e.EventAction != tt.action || e.DedupKey != tt.dedupKey {
+			t.Errorf("%d: expected %s of %s, got %+v", i, tt.action, tt.dedupKey, e)
+		}
+		if tt.action == "resolve" {
+			if e.Payload != nil {
+				t.Errorf("%d: expected no payload to resolve, got %+v", i, e.Payload)
+			}
+			continue
+		}
+		if e.Payload == nil || e.Payload.Severity != tt.severity || e.Payload.Summary != events[i].Message() {
+			t.Errorf("%d: unexpected payload %+v", i, e.Payload)
+		}
+	}
+}
+
+func TestDedupKey(t *testing.T) {
+	pod := api_v1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "foo-1"}
+	var Tests = []struct {
+		cluster  string
+		e        event.Event
+		expected string
+	}{
+		{"prod", event.Event{Name: "foo", Namespace: "default", Kind: "deployment"}, "prod/deployment/default/foo"},
+		{"", event.Event{Name: "foo", Namespace: "default", Kind: "deployment"}, "deployment/default/foo"},
+		{"", event.Event{Name: "node-1", Kind: "node"}, "node/node-1"},
+		{"", event.Event{Name: "foo-1.16a7b3c2d1e11", Namespace: "default", Kind: "Backoff", InvolvedObject: &pod}, "pod/default/foo-1"},
+	}
+
+	for _, tt := range Tests {
+		if got := dedupKey(tt.cluster, tt.e); got != tt.expected {
+			t.Errorf("dedupKey(%q, %s): expected %s, got %s", tt.cluster, tt.e.Name, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// DefaultStream is the stream the events are appended to by default.
+	DefaultStream = "kubewatch"
+	// DefaultMaxLen is the approximate maximum length of the stream by default.
+	DefaultMaxLen = 10000
+)
+
+// timeout is how long appending, or publishing, an event waits for the server.
+const timeout = 5 * time.Second
+
+var redisErrMsg = `
+%s
+
+You need to set the Redis url
+using "--url/-u" or using environment variables:
+
+export KW_REDIS_URL=redis_url
+
+Command line flags will override environment variables
+
+`
+
+// Redis handler implements handler.Handler interface,
+// Append events to a Redis stream, or publish them on a Pub/Sub channel
+type Redis struct {
+	Url     string
+	Stream  string
+	MaxLen  int64
+	Channel string
+
+	renderer *message.Renderer
+	client   *redis.Client
+}
+
+// Init prepares Redis configuration. The client connects on first use, and
+// reconnects by itself.
+func (r *Redis) Init(c *config.Config) error {
+	url := c.Handler.Redis.Url
+	stream := c.Handler.Redis.Stream
+	maxLen := int64(c.Handler.Redis.MaxLen)
+
+	if url == "" {
+		url = os.Getenv("KW_REDIS_URL")
+	}
+	if stream == "" {
+		stream = DefaultStream
+	}
+	if maxLen <= 0 {
+		maxLen = DefaultMaxLen
+	}
+
+	r.Url = url
+	r.Stream = stream
+	r.MaxLen = maxLen
+	r.Channel = c.Handler.Redis.Channel
+
+	if err := checkMissingRedisVars(r); err != nil {
+		return err
+	}
+
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	r.renderer = renderer
+	if c.Handler.Redis.Stream != "" && r.Channel != "" {
+		return fmt.Errorf("redis: stream and channel are exclusive, got %s and %s", r.Stream, r.Channel)
+	}
+	opts, err := redis.ParseURL(r.Url)
+	if err != nil {
+		return fmt.Errorf("redis: url: %v", err)
+	}
+	tlsConfig, err := c.Handler.Redis.TLS.Config()
+	if err != nil {
+		return fmt.Errorf("redis: tls.%v", err)
+	}
+	if tlsConfig != nil {
+		opts.TLSConfig = tlsConfig
+	}
+	r.client = redis.NewClient(opts)
+	return nil
+}
+
+// Handle handles an event.
+func (r *Redis) Handle(e event.Event) error {
+	data, err := json.Marshal(webhook.NewMessage(e, r.renderer.Message(e)))
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if r.Channel != "" {
+		if err := r.client.Publish(ctx, r.Channel, data).Err(); err != nil {
+			return redisError(err)
+		}
+		log.Printf("Message successfully published on %s at %s ", r.Channel, time.Now())
+		return nil
+	}
+
+	id, err := r.client.XAdd(ctx, prepareXAddArgs(e, data, r)).Result()
+	if err != nil {
+		return redisError(err)
+	}
+
+	log.Printf("Message %s successfully appended to %s at %s ", id, r.Stream, time.Now())
+	return nil
+}
+
+func checkMissingRedisVars(r *Redis) error {
+	if r.Url == "" {
+		return fmt.Errorf(redisErrMsg, "Missing Redis url")
+	}
+
+	return nil
+}
+
+// prepareXAddArgs returns the entry of an event in the stream: the fields
+// consumers filter events on, and the event as JSON
+func prepareXAddArgs(e event.Event, data []byte, r *Redis) *redis.XAddArgs {
+	kind, namespace, name := e.Subject()
+	return &redis.XAddArgs{
+		Stream: r.Stream,
+		MaxLen: r.MaxLen,
+		Approx: true,
+		Values: []interface{}{
+			"kind", kind,
+			"namespace", namespace,
+			"name", name,
+			"reason", e.Reason,
+			"event", string(data),
+		},
+	}
+}
+
+// redisError marks the errors of commands on a key of another type as
+// permanent: retrying them fails the same way
+func redisError(err error) error {
+	if _, ok := err.(redis.Error); ok && strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		return delivery.Permanent(err)
+	}
+	return err
+}
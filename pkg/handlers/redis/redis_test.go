@@ -0,0 +1,156 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/handlers/webhook"
+)
+
+func TestRedisInit(t *testing.T) {
+	s := &Redis{}
+	expectedError := fmt.Errorf(redisErrMsg, "Missing Redis url")
+
+	var Tests = []struct {
+		redis config.Redis
+		err   error
+	}{
+		{config.Redis{Url: "redis://localhost:6379/0"}, nil},
+		{config.Redis{Url: "redis://localhost:6379/0", Channel: "kubewatch"}, nil},
+		{config.Redis{Url: "redis://localhost:6379/0", Stream: "events", Channel: "kubewatch"}, fmt.Errorf("redis: stream and channel are exclusive, got events and kubewatch")},
+		{config.Redis{}, expectedError},
+	}
+
+	for _, tt := range Tests {
+		c := &config.Config{}
+		c.Handler.Redis = tt.redis
+		if err := s.Init(c); !reflect.DeepEqual(err, tt.err) {
+			t.Fatalf("Init(): %v", err)
+		}
+	}
+}
+
+func TestRedisStream(t *testing.T) {
+	m := miniredis.RunT(t)
+
+	c := &config.Config{}
+	c.Handler.Redis = config.Redis{Url: "redis://" + m.Addr(), Stream: "events", MaxLen: 2}
+	s := &Redis{}
+	if err := s.Init(c); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"foo", "bar", "baz"} {
+		if err := s.Handle(event.Event{Name: name, Namespace: "default", Kind: "pod", Reason: "Created"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := m.Stream("events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected the stream trimmed to 2 entries, got %d", len(entries))
+	}
+	values := entries[1].Values
+	expected := []string{"kind", "pod", "namespace", "default", "name", "baz", "reason", "Created", "event"}
+	if !reflect.DeepEqual(values[:len(expected)], expected) {
+		t.Errorf("unexpected entry %v", values)
+	}
+	var msg webhook.WebhookMessage
+	if err := json.Unmarshal([]byte(values[len(values)-1]), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.EventMeta.Name != "baz" || msg.EventMeta.Kind != "pod" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	// the stream key holds another type
+	m.Del("events")
+	m.Set("events", "foo")
+	err = s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+	if err == nil || !delivery.IsPermanent(err) {
+		t.Errorf("expected a permanent error, got %v", err)
+	}
+}
+
+func TestRedisPubSub(t *testing.T) {
+	m := miniredis.RunT(t)
+	sub := m.NewSubscriber()
+	defer sub.Close()
+	sub.Subscribe("kubewatch")
+	// miniredis replies to PUBLISH once the subscribers received the message
+	messages := make(chan miniredis.PubsubMessage, 1)
+	go func() {
+		if published, ok := <-sub.Messages(); ok {
+			messages <- published
+		}
+	}()
+
+	c := &config.Config{}
+	c.Handler.Redis = config.Redis{Url: "redis://" + m.Addr(), Channel: "kubewatch"}
+	s := &Redis{}
+	if err := s.Init(c); err != nil {
+		t.Fatal(err)
+	}
+	e := event.Event{Name: "foo", Namespace: "default", Kind: "deployment", Reason: "Updated", Status: "Warning"}
+	if err := s.Handle(e); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case published := <-messages:
+		var msg webhook.WebhookMessage
+		if err := json.Unmarshal([]byte(published.Message), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.EventMeta.Name != "foo" || msg.Text != e.Message() {
+			t.Errorf("unexpected message %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no message published")
+	}
+	if m.Exists(DefaultStream) {
+		t.Errorf("expected no stream with a channel")
+	}
+}
+
+func TestRedisUnavailable(t *testing.T) {
+	m := miniredis.RunT(t)
+	url := "redis://" + m.Addr()
+	m.Close()
+
+	c := &config.Config{}
+	c.Handler.Redis.Url = url
+	s := &Redis{}
+	if err := s.Init(c); err != nil {
+		t.Fatalf("expected Init() to succeed without server, got %v", err)
+	}
+	err := s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+	if err == nil || delivery.IsPermanent(err) {
+		t.Errorf("expected a transient error, got %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/health"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// Retry handler implements Handler interface,
+// delivering events to its handler in the background so that a slow or
+// failing service does not hold back the others, and retrying transient
+// failures with exponential backoff.
+// The events of an object are delivered in order: while its oldest event is
+// retried, the later ones wait, and the events of other objects go on.
+// With a spool, events are stored on disk until they are delivered, and the
+// ones that cannot be delivered are moved to its dead-letter store.
+// The events of a handler sending batches are delivered a batch at a time.
+type Retry struct {
+	name       string
+	handler    Handler
+	spool      *delivery.Spool
+	maxRetries int
+	workers    int
+	limiter    workqueue.RateLimiter
+	queue      workqueue.DelayingInterface
+	removeLive []func()
+	wg         sync.WaitGroup
+
+	// the queue holds the keys of the objects, and pending their events in order
+	mu      sync.Mutex
+	pending map[string][]*delivery.Record
+}
+
+// retryTimeout is how long a single delivery attempt may take before the handler is considered wedged
+const retryTimeout = 5 * time.Minute
+
+// NewRetry returns a handler retrying the deliveries of h as configured,
+// spool may be nil
+func NewRetry(name string, h Handler, c config.Delivery, spool *delivery.Spool) (*Retry, error) {
+	maxRetries, backoff, maxBackoff, err := c.Retries()
+	if err != nil {
+		return nil, err
+	}
+	return &Retry{
+		name:       name,
+		handler:    h,
+		spool:      spool,
+		maxRetries: maxRetries,
+		limiter:    workqueue.NewItemExponentialFailureRateLimiter(backoff, maxBackoff),
+		queue:      workqueue.NewNamedDelayingQueue(name),
+		pending:    map[string][]*delivery.Record{},
+	}, nil
+}
+
+// Init initializes the handler and starts delivering events,
+// beginning with the ones left in the spool
+func (r *Retry) Init(c *config.Config) error {
+	if err := r.handler.Init(c); err != nil {
+		return err
+	}
+	if r.spool != nil {
+		pending, err := r.spool.Pending(r.name)
+		if err != nil {
+			return fmt.Errorf("%s: reading pending events: %v", r.name, err)
+		}
+		if len(pending) > 0 {
+			logrus.Infof("%s: resuming delivery of %d pending events", r.name, len(pending))
+		}
+		for _, rec := range pending {
+			r.add(rec)
+		}
+	}
+	// the queue hands an object to a single worker at a time, which keeps
+	// the events of each object in order
+	r.workers = batchSize(r.handler)
+	for i := 0; i < r.workers; i++ {
+		activity := &health.Activity{Timeout: retryTimeout}
+		r.removeLive = append(r.removeLive, health.AddLivenessCheck("handler "+r.name, activity.Check))
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			for r.processNextItem(activity) {
+			}
+		}()
+	}
+	return nil
+}
+
+// batchSize returns how many events a handler sends at once, 1 unless it
+// implements Batcher
+func batchSize(h Handler) int {
+	if i, ok := h.(*Instance); ok {
+		h = i.Handler
+	}
+	if b, ok := h.(Batcher); ok && b.BatchSize() > 1 {
+		return b.BatchSize()
+	}
+	return 1
+}
+
+// Handle queues the event for delivery and returns immediately,
+// once the event is stored in the spool if any
+func (r *Retry) Handle(e event.Event) error {
+	if r.spool == nil {
+		r.add(&delivery.Record{Handler: r.name, Event: e})
+		return nil
+	}
+	rec, err := r.spool.Add(r.name, e)
+	if err != nil {
+		return fmt.Errorf("%s: storing event: %v", r.name, err)
+	}
+	r.add(rec)
+	return nil
+}
+
+// objectKey identifies the object an event is about
+func objectKey(e event.Event) string {
+	kind, namespace, name := e.Subject()
+	return strings.Join([]string{kind, namespace, name}, "/")
+}
+
+// add queues an event after the other events of its object, if any
+func (r *Retry) add(rec *delivery.Record) {
+	key := objectKey(rec.Event)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending[key] = append(r.pending[key], rec)
+	if len(r.pending[key]) == 1 {
+		r.queue.Add(key)
+	}
+}
+
+// head returns the oldest event of an object
+func (r *Retry) head(key string) *delivery.Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pending[key][0]
+}
+
+// next removes the oldest event of an object, and queues the object again
+// if it has more events
+func (r *Retry) next(key string) {
+	r.limiter.Forget(key)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.pending[key]) > 1 {
+		r.pending[key] = r.pending[key][1:]
+		r.queue.Add(key)
+		return
+	}
+	delete(r.pending, key)
+}
+
+// ShutDown stops delivering events; without a spool, the ones still queued are dropped
+func (r *Retry) ShutDown() {
+	r.queue.ShutDown()
+	for _, remove := range r.removeLive {
+		remove()
+	}
+}
+
+// Close stops delivering events like ShutDown, and closes the handler once the
+// deliveries in progress are done
+func (r *Retry) Close() error {
+	r.ShutDown()
+	r.wg.Wait()
+	return Close(r.handler)
+}
+
+func (r *Retry) processNextItem(activity *health.Activity) bool {
+	item, quit := r.queue.Get()
+	if quit {
+		return false
+	}
+	defer r.queue.Done(item)
+
+	key := item.(string)
+	rec := r.head(key)
+	e := rec.Event
+	start := time.Now()
+	activity.Start()
+	err := r.handler.Handle(e)
+	activity.Done()
+	metrics.HandlerDuration.WithLabelValues(r.name).Observe(time.Since(start).Seconds())
+	switch {
+	case err == nil:
+		metrics.NotificationsSent.WithLabelValues(r.name).Inc()
+		r.done(rec)
+		r.next(key)
+	case delivery.IsPermanent(err):
+		metrics.NotificationsFailed.WithLabelValues(r.name, "permanent").Inc()
+		metrics.NotificationsDropped.WithLabelValues(r.name).Inc()
+		logrus.Errorf("%s: permanent failure delivering %s event for %s, %s: %v", r.name, e.Reason, e.Name, r.drop(rec, err), err)
+		r.next(key)
+	case r.limiter.NumRequeues(item) < r.maxRetries:
+		metrics.NotificationsFailed.WithLabelValues(r.name, "transient").Inc()
+		delay := r.limiter.When(item)
+		if d := delivery.Delay(err); d > delay {
+			delay = d
+		}
+		logrus.Warnf("%s: failed delivering %s event for %s (will retry in %s): %v", r.name, e.Reason, e.Name, delay, err)
+		r.queue.AddAfter(item, delay)
+	default:
+		metrics.NotificationsFailed.WithLabelValues(r.name, "transient").Inc()
+		metrics.NotificationsDropped.WithLabelValues(r.name).Inc()
+		logrus.Errorf("%s: failed delivering %s event for %s (giving up after %d retries), %s: %v", r.name, e.Reason, e.Name, r.maxRetries, r.drop(rec, err), err)
+		r.next(key)
+	}
+	return true
+}
+
+func (r *Retry) done(rec *delivery.Record) {
+	if r.spool == nil {
+		return
+	}
+	if err := r.spool.Done(rec); err != nil {
+		logrus.Errorf("%s: removing delivered event %s: %v", r.name, rec.ID, err)
+	}
+}
+
+// drop moves an event that cannot be delivered to the dead-letter store, if
+// any, and tells what became of it
+func (r *Retry) drop(rec *delivery.Record, reason error) string {
+	if r.spool == nil {
+		return "dropping it"
+	}
+	if err := r.spool.Bury(rec, reason); err != nil {
+		logrus.Errorf("%s: moving event %s to the dead-letter store: %v", r.name, rec.ID, err)
+		return "leaving it pending"
+	}
+	return fmt.Sprintf("moved to the dead-letter store as %s", rec.ID)
+}
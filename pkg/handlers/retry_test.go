@@ -0,0 +1,400 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// flaky fails with the given errors before succeeding,
+// and tells when the expected number of attempts is reached
+type flaky struct {
+	mu       sync.Mutex
+	errs     []error
+	expected int
+	attempts []time.Time
+	done     chan struct{}
+}
+
+func newFlaky(expected int, errs ...error) *flaky {
+	return &flaky{errs: errs, expected: expected, done: make(chan struct{})}
+}
+
+func (f *flaky) Init(c *config.Config) error {
+	return nil
+}
+
+func (f *flaky) Handle(e event.Event) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts = append(f.attempts, time.Now())
+	if len(f.attempts) == f.expected {
+		close(f.done)
+	}
+	if len(f.attempts) <= len(f.errs) {
+		return f.errs[len(f.attempts)-1]
+	}
+	return nil
+}
+
+func (f *flaky) wait(t *testing.T) int {
+	select {
+	case <-f.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the delivery")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.attempts)
+}
+
+func newTestRetry(t *testing.T, name string, h Handler) *Retry {
+	r, err := NewRetry(name, h, config.Delivery{Backoff: "1ms", MaxBackoff: "10ms"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Init(&config.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRetryTransient(t *testing.T) {
+	h := newFlaky(3, fmt.Errorf("connection refused"), fmt.Errorf("connection refused"))
+	r := newTestRetry(t, "transient-test", h)
+	defer r.ShutDown()
+
+	if err := r.Handle(event.Event{Name: "foo"}); err != nil {
+		t.Fatalf("Handle(): %v", err)
+	}
+	if attempts := h.wait(t); attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if failed := testutil.ToFloat64(metrics.NotificationsFailed.WithLabelValues("transient-test", "transient")); failed != 2 {
+		t.Errorf("expected 2 failed attempts to be counted, got %v", failed)
+	}
+	if sent := testutil.ToFloat64(metrics.NotificationsSent.WithLabelValues("transient-test")); sent != 1 {
+		t.Errorf("expected 1 notification sent to be counted, got %v", sent)
+	}
+}
+
+func TestRetryPermanent(t *testing.T) {
+	h := newFlaky(1, delivery.Permanent(fmt.Errorf("channel_not_found")))
+	r := newTestRetry(t, "test", h)
+	defer r.ShutDown()
+
+	r.Handle(event.Event{Name: "foo"})
+	if attempts := h.wait(t); attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+	time.Sleep(20 * time.Millisecond)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.attempts) != 1 {
+		t.Errorf("expected a permanent failure not to be retried, got %d attempts", len(h.attempts))
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	retryAfter := 100 * time.Millisecond
+	h := newFlaky(2, delivery.RetryAfter(fmt.Errorf("rate limited"), retryAfter))
+	r := newTestRetry(t, "test", h)
+	defer r.ShutDown()
+
+	r.Handle(event.Event{Name: "foo"})
+	if attempts := h.wait(t); attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+	if d := h.attempts[1].Sub(h.attempts[0]); d < retryAfter {
+		t.Errorf("expected the retry to wait for %s, waited %s", retryAfter, d)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var errs []error
+	for i := 0; i < 10; i++ {
+		errs = append(errs, fmt.Errorf("unavailable"))
+	}
+	h := newFlaky(3, errs...)
+	r, err := NewRetry("test", h, config.Delivery{MaxRetries: 2, Backoff: "1ms"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Init(&config.Config{})
+	defer r.ShutDown()
+
+	r.Handle(event.Event{Name: "foo"})
+	h.wait(t)
+	time.Sleep(50 * time.Millisecond)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.attempts) != 3 {
+		t.Errorf("expected 3 attempts, got %d", len(h.attempts))
+	}
+}
+
+// ordered fails the first attempt of each event in fail, and records the
+// events it delivers in order
+type ordered struct {
+	mu        sync.Mutex
+	fail      map[string]bool
+	delivered []string
+	done      chan struct{}
+	expected  int
+}
+
+func (o *ordered) Init(c *config.Config) error {
+	return nil
+}
+
+func (o *ordered) Handle(e event.Event) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	id := e.Name + " " + e.Reason
+	if o.fail[id] {
+		o.fail[id] = false
+		return fmt.Errorf("unavailable")
+	}
+	o.delivered = append(o.delivered, id)
+	if len(o.delivered) == o.expected {
+		close(o.done)
+	}
+	return nil
+}
+
+func TestRetryOrder(t *testing.T) {
+	h := &ordered{fail: map[string]bool{"foo Created": true}, done: make(chan struct{}), expected: 3}
+	r, err := NewRetry("test", h, config.Delivery{Backoff: "50ms", MaxBackoff: "50ms"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Init(&config.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	defer r.ShutDown()
+
+	r.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+	r.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Updated"})
+	r.Handle(event.Event{Name: "bar", Kind: "pod", Reason: "Created"})
+	select {
+	case <-h.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the deliveries")
+	}
+
+	// foo is retried before its next event, and does not hold back bar
+	expected := []string{"bar Created", "foo Created", "foo Updated"}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !reflect.DeepEqual(h.delivered, expected) {
+		t.Errorf("expected deliveries %v, got %v", expected, h.delivered)
+	}
+}
+
+// batching delivers events in batches of two, each Handle call waiting for
+// the batch to be sent
+type batching struct {
+	mu        sync.Mutex
+	batch     []chan struct{}
+	delivered []string
+}
+
+func (b *batching) Init(c *config.Config) error {
+	return nil
+}
+
+func (b *batching) BatchSize() int {
+	return 2
+}
+
+func (b *batching) Handle(e event.Event) error {
+	sent := make(chan struct{})
+	b.mu.Lock()
+	b.delivered = append(b.delivered, e.Name+" "+e.Reason)
+	b.batch = append(b.batch, sent)
+	if len(b.batch) == b.BatchSize() {
+		for _, c := range b.batch {
+			close(c)
+		}
+		b.batch = nil
+	}
+	b.mu.Unlock()
+	select {
+	case <-sent:
+		return nil
+	case <-time.After(5 * time.Second):
+		return delivery.Permanent(fmt.Errorf("batch not sent"))
+	}
+}
+
+func TestRetryBatch(t *testing.T) {
+	h := &batching{}
+	r := newTestRetry(t, "test", h)
+	defer r.ShutDown()
+
+	for _, e := range []event.Event{
+		{Name: "foo", Kind: "pod", Reason: "Created"},
+		{Name: "foo", Kind: "pod", Reason: "Updated"},
+		{Name: "bar", Kind: "pod", Reason: "Created"},
+		{Name: "bar", Kind: "pod", Reason: "Updated"},
+	} {
+		r.Handle(e)
+	}
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		h.mu.Lock()
+		delivered := append([]string(nil), h.delivered...)
+		h.mu.Unlock()
+		if len(delivered) == 4 || time.Now().After(deadline) {
+			// the events of both objects fill the batches, each object in order
+			sort.Strings(delivered)
+			expected := []string{"bar Created", "bar Updated", "foo Created", "foo Updated"}
+			if !reflect.DeepEqual(delivered, expected) {
+				t.Fatalf("expected deliveries %v, got %v", expected, delivered)
+			}
+			break
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if index(h.delivered, "foo Created") > index(h.delivered, "foo Updated") || index(h.delivered, "bar Created") > index(h.delivered, "bar Updated") {
+		t.Errorf("expected the events of each object in order, got %v", h.delivered)
+	}
+}
+
+func index(list []string, s string) int {
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// closing records when it is closed, after the deliveries
+type closing struct {
+	ordered
+	closed []string
+}
+
+func (c *closing) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = append([]string(nil), c.delivered...)
+	return nil
+}
+
+func TestRetryClose(t *testing.T) {
+	h := &closing{ordered: ordered{done: make(chan struct{}), expected: 1}}
+	r := newTestRetry(t, "test", h)
+	r.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+	<-h.done
+
+	if err := Close(NewFanout(r)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"foo Created"}; !reflect.DeepEqual(h.closed, expected) {
+		t.Errorf("expected the handler closed after delivering %v, got %v", expected, h.closed)
+	}
+}
+
+func TestRetryDoesNotBlock(t *testing.T) {
+	blocked := make(chan struct{})
+	slow := &blocking{release: blocked}
+	r := newTestRetry(t, "test", slow)
+	defer r.ShutDown()
+	defer close(blocked)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		r.Handle(event.Event{Name: fmt.Sprintf("foo-%d", i)})
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("expected Handle to return immediately, took %s", d)
+	}
+}
+
+type blocking struct {
+	release chan struct{}
+}
+
+func (b *blocking) Init(c *config.Config) error {
+	return nil
+}
+
+func (b *blocking) Handle(e event.Event) error {
+	<-b.release
+	return nil
+}
+
+func TestRetrySpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubewatch-spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	spool, err := delivery.OpenSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// left over by a previous run
+	if _, err := spool.Add("test", event.Event{Name: "foo"}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := newFlaky(2, nil, delivery.Permanent(fmt.Errorf("rejected")))
+	r, err := NewRetry("test", h, config.Delivery{Backoff: "1ms"}, spool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Init(&config.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	defer r.ShutDown()
+
+	r.Handle(event.Event{Name: "bar"})
+	if attempts := h.wait(t); attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+	// the event is dead-lettered after the attempt
+	pending, _ := spool.Pending("test")
+	for deadline := time.Now().Add(time.Second); len(pending) != 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		pending, _ = spool.Pending("test")
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected no pending events, got %+v", pending)
+	}
+	dead, _ := spool.Dead("test")
+	if len(dead) != 1 || dead[0].Event.Name != "bar" || dead[0].Error != "rejected" {
+		t.Errorf("expected bar to be dead-lettered, got %+v", dead)
+	}
+}
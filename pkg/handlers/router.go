@@ -0,0 +1,157 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+)
+
+// Router handler implements Handler interface,
+// delivering each event to the handlers of the routes it matches
+type Router struct {
+	routes       []route
+	destinations map[string]Handler
+}
+
+type route struct {
+	namespaces   []*regexp.Regexp
+	match        config.RouteMatch
+	destinations []string
+}
+
+// NewRouter returns a handler routing events to the named destinations
+func NewRouter(routes []config.Route, destinations map[string]Handler) (*Router, error) {
+	r := &Router{destinations: destinations}
+	for i, cr := range routes {
+		rt := route{match: cr.Match, destinations: cr.Destinations}
+		for _, name := range cr.Destinations {
+			if _, ok := destinations[name]; !ok {
+				return nil, fmt.Errorf("routes[%d]: unknown destination %q, configured handlers: %s", i, name, strings.Join(r.names(), ", "))
+			}
+		}
+		for _, p := range cr.Match.Namespaces {
+			re, err := config.NamespacePattern(p)
+			if err != nil {
+				return nil, fmt.Errorf("routes[%d]: %v", i, err)
+			}
+			rt.namespaces = append(rt.namespaces, re)
+		}
+		r.routes = append(r.routes, rt)
+	}
+	return r, nil
+}
+
+func (r *Router) names() []string {
+	names := make([]string, 0, len(r.destinations))
+	for name := range r.destinations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Init initializes every destination handler
+func (r *Router) Init(c *config.Config) error {
+	for _, name := range r.names() {
+		if err := r.destinations[name].Init(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handle delivers the event to the destinations of every matching route
+func (r *Router) Handle(e event.Event) error {
+	var selected []Handler
+	seen := map[string]bool{}
+	for _, rt := range r.routes {
+		if !rt.matches(e) {
+			continue
+		}
+		for _, name := range rt.destinations {
+			if !seen[name] {
+				seen[name] = true
+				selected = append(selected, r.destinations[name])
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		logrus.Debugf("No route for %s event of %s %s", e.Reason, e.Kind, e.Name)
+		return nil
+	}
+	return NewFanout(selected...).Handle(e)
+}
+
+// Close closes every destination handler
+func (r *Router) Close() error {
+	destinations := make([]Handler, 0, len(r.destinations))
+	for _, name := range r.names() {
+		destinations = append(destinations, r.destinations[name])
+	}
+	return NewFanout(destinations...).Close()
+}
+
+func (rt route) matches(e event.Event) bool {
+	if len(rt.namespaces) > 0 && !anyRegexp(rt.namespaces, e.Namespace) {
+		return false
+	}
+	if len(rt.match.Kinds) > 0 && !anyFold(rt.match.Kinds, e.Kind) {
+		return false
+	}
+	if len(rt.match.Reasons) > 0 && !anyFold(rt.match.Reasons, e.Reason) {
+		return false
+	}
+	if len(rt.match.Statuses) > 0 && !anyFold(rt.match.Statuses, e.Status) {
+		return false
+	}
+	return hasAll(e.Labels, rt.match.Labels) && hasAll(e.Annotations, rt.match.Annotations)
+}
+
+func anyRegexp(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAll(have, want map[string]string) bool {
+	for k, v := range want {
+		if got, ok := have[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestNewRouterUnknownDestination(t *testing.T) {
+	routes := []config.Route{{Destinations: []string{"pagerduty"}}}
+	if _, err := NewRouter(routes, map[string]Handler{"slack": &recorder{}}); err == nil {
+		t.Fatalf("expected an error for an unknown destination")
+	}
+}
+
+func TestRouterHandle(t *testing.T) {
+	routes := []config.Route{
+		{
+			Match:        config.RouteMatch{Namespaces: []string{"payments-*"}},
+			Destinations: []string{"payments"},
+		},
+		{
+			Match:        config.RouteMatch{Kinds: []string{"Node"}},
+			Destinations: []string{"sre"},
+		},
+		{
+			Match: config.RouteMatch{
+				Reasons: []string{"Deleted"},
+				Labels:  map[string]string{"env": "prod"},
+			},
+			Destinations: []string{"sre", "pager"},
+		},
+	}
+
+	var Tests = []struct {
+		name     string
+		e        event.Event
+		payments int
+		sre      int
+		pager    int
+	}{
+		{"namespace glob", event.Event{Namespace: "payments-eu", Kind: "pod", Reason: "Created"}, 1, 0, 0},
+		{"kind, case insensitive", event.Event{Kind: "node", Reason: "Updated"}, 0, 1, 0},
+		{"several routes, delivered once", event.Event{Kind: "node", Reason: "Deleted", Labels: map[string]string{"env": "prod"}}, 0, 1, 1},
+		{"missing label", event.Event{Namespace: "default", Kind: "pod", Reason: "Deleted"}, 0, 0, 0},
+	}
+
+	for _, tt := range Tests {
+		payments, sre, pager := &recorder{}, &recorder{}, &recorder{}
+		r, err := NewRouter(routes, map[string]Handler{"payments": payments, "sre": sre, "pager": pager})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r.Handle(tt.e)
+
+		if len(payments.events) != tt.payments || len(sre.events) != tt.sre || len(pager.events) != tt.pager {
+			t.Errorf("%s: expected %d/%d/%d events, got %d/%d/%d", tt.name,
+				tt.payments, tt.sre, tt.pager,
+				len(payments.events), len(sre.events), len(pager.events))
+		}
+	}
+}
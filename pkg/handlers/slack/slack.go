@@ -19,12 +19,15 @@ package slack
 import (
 	"fmt"
 	"log"
+	"net"
 	"os"
 
 	"github.com/slack-go/slack"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
 )
 
 var slackColors = map[string]string{
@@ -52,6 +55,8 @@ type Slack struct {
 	Token   string
 	Channel string
 	Title   string
+
+	renderer *message.Renderer
 }
 
 // Init prepares slack configuration
@@ -79,11 +84,17 @@ func (s *Slack) Init(c *config.Config) error {
 	s.Channel = channel
 	s.Title = title
 
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	s.renderer = renderer
+
 	return checkMissingSlackVars(s)
 }
 
 // Handle handles the notification.
-func (s *Slack) Handle(e event.Event) {
+func (s *Slack) Handle(e event.Event) error {
 	api := slack.New(s.Token)
 	attachment := prepareSlackAttachment(e, s)
 
@@ -91,11 +102,29 @@ func (s *Slack) Handle(e event.Event) {
 		slack.MsgOptionAttachments(attachment),
 		slack.MsgOptionAsUser(true))
 	if err != nil {
-		log.Printf("%s\n", err)
-		return
+		return classifyError(err)
 	}
 
 	log.Printf("Message successfully sent to channel %s at %s", channelID, timestamp)
+	return nil
+}
+
+// classifyError tells apart transient failures, such as rate limiting, 5xx
+// responses or network errors, from errors reported by the Slack API,
+// such as channel_not_found or invalid_auth, that retrying will not fix.
+func classifyError(err error) error {
+	switch e := err.(type) {
+	case *slack.RateLimitedError:
+		return delivery.RetryAfter(err, e.RetryAfter)
+	case interface{ Retryable() bool }:
+		if e.Retryable() {
+			return err
+		}
+		return delivery.Permanent(err)
+	case net.Error:
+		return err
+	}
+	return delivery.Permanent(err)
 }
 
 func checkMissingSlackVars(s *Slack) error {
@@ -112,11 +141,18 @@ func prepareSlackAttachment(e event.Event, s *Slack) slack.Attachment {
 		Fields: []slack.AttachmentField{
 			{
 				Title: s.Title,
-				Value: e.Message(),
+				Value: s.renderer.Message(e),
 			},
 		},
 	}
 
+	for _, c := range e.Diff {
+		attachment.Fields = append(attachment.Fields, slack.AttachmentField{
+			Title: c.Path,
+			Value: fmt.Sprintf("`%s` → `%s`", c.OldValue(), c.NewValue()),
+		})
+	}
+
 	if color, ok := slackColors[e.Status]; ok {
 		attachment.Color = color
 	}
@@ -22,13 +22,16 @@ See example configuration in the ConfigExample constant.
 package smtp
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"net/textproto"
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
-	"github.com/sirupsen/logrus"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
 )
 
 const (
@@ -52,6 +55,8 @@ const (
 // Notify event via email.
 type SMTP struct {
 	cfg config.SMTP
+
+	renderer *message.Renderer
 }
 
 // Init prepares Webhook configuration
@@ -67,21 +72,42 @@ func (s *SMTP) Init(c *config.Config) error {
 	if s.cfg.Smarthost == "" {
 		return fmt.Errorf("smtp `smarthost` conf field is required")
 	}
+
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	s.renderer = renderer
 	return nil
 }
 
 // Handle handles the notification.
-func (s *SMTP) Handle(e event.Event) {
-	send(s.cfg, e.Message())
+func (s *SMTP) Handle(e event.Event) error {
+	msg, err := formatEmail(e, s.renderer)
+	if err != nil {
+		return delivery.Permanent(err)
+	}
+	if err := send(s.cfg, msg); err != nil {
+		return err
+	}
 	log.Printf("Message successfully sent to %s at %s ", s.cfg.To, time.Now())
+	return nil
 }
 
-func formatEmail(e event.Event) (string, error) {
-	return e.Message(), nil
+func formatEmail(e event.Event, renderer *message.Renderer) (string, error) {
+	msg := renderer.Message(e)
+	if len(e.Diff) > 0 {
+		msg += "\n\nChanges:\n" + e.DiffMessage()
+	}
+	return msg, nil
 }
 
-func send(conf config.SMTP, msg string) {
-	if err := sendEmail(conf, msg); err != nil {
-		logrus.Error(err)
+// send sends the email, treating 5xx replies of the server as permanent failures.
+func send(conf config.SMTP, msg string) error {
+	err := sendEmail(conf, msg)
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return delivery.Permanent(err)
 	}
+	return err
 }
@@ -27,7 +27,10 @@ import (
 	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
 	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/bitnami-labs/kubewatch/pkg/message"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var webhookErrMsg = `
@@ -46,21 +49,35 @@ Command line flags will override environment variables
 // Notify event to Webhook channel
 type Webhook struct {
 	Url string
+
+	renderer *message.Renderer
 }
 
 // WebhookMessage for messages
 type WebhookMessage struct {
-	EventMeta EventMeta `json:"eventmeta"`
-	Text      string    `json:"text"`
-	Time      time.Time `json:"time"`
+	EventMeta EventMeta      `json:"eventmeta"`
+	Text      string         `json:"text"`
+	Time      time.Time      `json:"time"`
+	Diff      []event.Change `json:"diff,omitempty"`
+	// Old and new versions of the object, if enabled with includeObjects
+	OldObject json.RawMessage `json:"oldObject,omitempty"`
+	Object    json.RawMessage `json:"object,omitempty"`
 }
 
 // EventMeta containes the meta data about the event occurred
 type EventMeta struct {
-	Kind      string `json:"kind"`
-	Name      string `json:"name"`
-	Namespace string `json:"namespace"`
-	Reason    string `json:"reason"`
+	Kind            string                   `json:"kind"`
+	Name            string                   `json:"name"`
+	Namespace       string                   `json:"namespace"`
+	Reason          string                   `json:"reason"`
+	Status          string                   `json:"status"`
+	UID             string                   `json:"uid,omitempty"`
+	APIVersion      string                   `json:"apiVersion,omitempty"`
+	ResourceVersion string                   `json:"resourceVersion,omitempty"`
+	Labels          map[string]string        `json:"labels,omitempty"`
+	Annotations     map[string]string        `json:"annotations,omitempty"`
+	OwnerReferences []meta_v1.OwnerReference `json:"ownerReferences,omitempty"`
+	Timestamp       time.Time                `json:"timestamp"`
 }
 
 // Init prepares Webhook configuration
@@ -73,20 +90,26 @@ func (m *Webhook) Init(c *config.Config) error {
 
 	m.Url = url
 
+	renderer, err := message.NewRenderer(c.Template.Message, c.Template.Kinds)
+	if err != nil {
+		return err
+	}
+	m.renderer = renderer
+
 	return checkMissingWebhookVars(m)
 }
 
 // Handle handles an event.
-func (m *Webhook) Handle(e event.Event) {
+func (m *Webhook) Handle(e event.Event) error {
 	webhookMessage := prepareWebhookMessage(e, m)
 
 	err := postMessage(m.Url, webhookMessage)
 	if err != nil {
-		log.Printf("%s\n", err)
-		return
+		return err
 	}
 
 	log.Printf("Message successfully sent to %s at %s ", m.Url, time.Now())
+	return nil
 }
 
 func checkMissingWebhookVars(s *Webhook) error {
@@ -98,35 +121,53 @@ func checkMissingWebhookVars(s *Webhook) error {
 }
 
 func prepareWebhookMessage(e event.Event, m *Webhook) *WebhookMessage {
+	return NewMessage(e, m.renderer.Message(e))
+}
+
+// NewMessage returns the webhook payload of an event with the given text,
+// which other handlers publishing events as JSON share
+func NewMessage(e event.Event, text string) *WebhookMessage {
 	return &WebhookMessage{
 		EventMeta: EventMeta{
-			Kind:      e.Kind,
-			Name:      e.Name,
-			Namespace: e.Namespace,
-			Reason:    e.Reason,
+			Kind:            e.Kind,
+			Name:            e.Name,
+			Namespace:       e.Namespace,
+			Reason:          e.Reason,
+			Status:          e.Status,
+			UID:             e.UID,
+			APIVersion:      e.APIVersion,
+			ResourceVersion: e.ResourceVersion,
+			Labels:          e.Labels,
+			Annotations:     e.Annotations,
+			OwnerReferences: e.OwnerReferences,
+			Timestamp:       e.Timestamp,
 		},
-		Text: e.Message(),
-		Time: time.Now(),
+		Text:      text,
+		Time:      time.Now(),
+		Diff:      e.Diff,
+		OldObject: e.OldObject,
+		Object:    e.Object,
 	}
 }
 
 func postMessage(url string, webhookMessage *WebhookMessage) error {
 	message, err := json.Marshal(webhookMessage)
 	if err != nil {
-		return err
+		return delivery.Permanent(err)
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(message))
 	if err != nil {
-		return err
+		return delivery.Permanent(err)
 	}
 	req.Header.Add("Content-Type", "application/json")
 
 	client := &http.Client{}
-	_, err = client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	return nil
+	return delivery.CheckResponse(resp)
 }
@@ -17,11 +17,18 @@ limitations under the License.
 package webhook
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/bitnami-labs/kubewatch/config"
+	"github.com/bitnami-labs/kubewatch/pkg/delivery"
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestWebhookInit(t *testing.T) {
@@ -44,3 +51,85 @@ func TestWebhookInit(t *testing.T) {
 		}
 	}
 }
+
+func TestWebhookHandle(t *testing.T) {
+	var Tests = []struct {
+		status    int
+		isErr     bool
+		permanent bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusServiceUnavailable, true, false},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusBadRequest, true, true},
+	}
+
+	for _, tt := range Tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+
+		s := &Webhook{Url: ts.URL}
+		err := s.Handle(event.Event{Name: "foo", Kind: "pod", Reason: "Created"})
+		ts.Close()
+
+		if (err != nil) != tt.isErr {
+			t.Fatalf("%d: unexpected error %v", tt.status, err)
+		}
+		if got := delivery.IsPermanent(err); got != tt.permanent {
+			t.Errorf("%d: expected IsPermanent() to be %v, got %v", tt.status, tt.permanent, got)
+		}
+	}
+}
+
+func TestWebhookMessage(t *testing.T) {
+	var got map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer ts.Close()
+
+	e := event.Event{
+		Name:            "foo",
+		Namespace:       "default",
+		Kind:            "pod",
+		Reason:          "Deleted",
+		Status:          "Danger",
+		UID:             "1b0c5c8e-7d4a-4bf6-a0e5-2bb8f4f3c2a1",
+		APIVersion:      "v1",
+		ResourceVersion: "42",
+		Labels:          map[string]string{"app": "foo"},
+		OwnerReferences: []meta_v1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "foo-6d4cf56db6"}},
+		Timestamp:       time.Date(2020, 11, 2, 10, 15, 0, 0, time.UTC),
+		OldObject:       json.RawMessage(`{"kind":"Pod"}`),
+	}
+	s := &Webhook{Url: ts.URL}
+	if err := s.Handle(e); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"kind":            "pod",
+		"name":            "foo",
+		"namespace":       "default",
+		"reason":          "Deleted",
+		"status":          "Danger",
+		"uid":             "1b0c5c8e-7d4a-4bf6-a0e5-2bb8f4f3c2a1",
+		"apiVersion":      "v1",
+		"resourceVersion": "42",
+		"labels":          map[string]interface{}{"app": "foo"},
+		"ownerReferences": []interface{}{map[string]interface{}{"apiVersion": "apps/v1", "kind": "ReplicaSet", "name": "foo-6d4cf56db6", "uid": ""}},
+		"timestamp":       "2020-11-02T10:15:00Z",
+	}
+	if !reflect.DeepEqual(got["eventmeta"], expected) {
+		t.Errorf("expected eventmeta %v, got %v", expected, got["eventmeta"])
+	}
+	if !reflect.DeepEqual(got["oldObject"], map[string]interface{}{"kind": "Pod"}) {
+		t.Errorf("expected the old object, got %v", got["oldObject"])
+	}
+	if _, ok := got["object"]; ok {
+		t.Errorf("expected no new object for a delete")
+	}
+}
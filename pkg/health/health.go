@@ -0,0 +1,160 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package health keeps track of the liveness and readiness of kubewatch.
+
+Components register checks while they run: liveness checks fail when the
+process must be restarted, e.g. because a worker is wedged, and readiness
+checks fail until the component is able to do its job, e.g. until an
+informer cache has synced.
+*/
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check returns an error when the component it checks is not healthy or ready.
+type Check func() error
+
+type registry struct {
+	mu     sync.Mutex
+	next   int
+	checks map[int]namedCheck
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+var (
+	liveness  = &registry{checks: map[int]namedCheck{}}
+	readiness = &registry{checks: map[int]namedCheck{}}
+)
+
+// AddLivenessCheck registers a liveness check and returns a function removing it.
+func AddLivenessCheck(name string, check Check) (remove func()) {
+	return liveness.add(name, check)
+}
+
+// AddReadinessCheck registers a readiness check and returns a function removing it.
+func AddReadinessCheck(name string, check Check) (remove func()) {
+	return readiness.add(name, check)
+}
+
+// Live runs the liveness checks.
+func Live() error {
+	return liveness.run()
+}
+
+// Ready runs the readiness checks.
+func Ready() error {
+	return readiness.run()
+}
+
+// LivenessHandler serves the result of the liveness checks, for /healthz.
+func LivenessHandler() http.Handler {
+	return handler(Live)
+}
+
+// ReadinessHandler serves the result of the readiness checks, for /readyz.
+func ReadinessHandler() http.Handler {
+	return handler(Ready)
+}
+
+func handler(run func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := run(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, err)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
+
+func (r *registry) add(name string, check Check) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := r.next
+	r.next++
+	r.checks[id] = namedCheck{name: name, check: check}
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.checks, id)
+	}
+}
+
+func (r *registry) run() error {
+	r.mu.Lock()
+	checks := make([]namedCheck, 0, len(r.checks))
+	for _, c := range r.checks {
+		checks = append(checks, c)
+	}
+	r.mu.Unlock()
+
+	var failures []string
+	for _, c := range checks {
+		if err := c.check(); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", c.name, err))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	sort.Strings(failures)
+	return fmt.Errorf("%s", strings.Join(failures, "\n"))
+}
+
+// Activity tracks the item a worker is processing, to tell when it is wedged.
+type Activity struct {
+	// Timeout after which a worker still processing the same item is wedged.
+	Timeout time.Duration
+
+	since int64
+}
+
+// Start records that the worker started processing an item.
+func (a *Activity) Start() {
+	atomic.StoreInt64(&a.since, time.Now().UnixNano())
+}
+
+// Done records that the worker is done with the item.
+func (a *Activity) Done() {
+	atomic.StoreInt64(&a.since, 0)
+}
+
+// Check fails when the worker has been processing the same item for longer than Timeout.
+func (a *Activity) Check() error {
+	since := atomic.LoadInt64(&a.since)
+	if since == 0 {
+		return nil
+	}
+	if d := time.Since(time.Unix(0, since)); d > a.Timeout {
+		return fmt.Errorf("worker busy with the same item for %s", d.Round(time.Second))
+	}
+	return nil
+}
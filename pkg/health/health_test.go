@@ -0,0 +1,97 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChecks(t *testing.T) {
+	if err := Ready(); err != nil {
+		t.Fatalf("expected no readiness check to fail, got %v", err)
+	}
+
+	removeA := AddReadinessCheck("informer pod", func() error { return fmt.Errorf("cache not synced") })
+	removeB := AddReadinessCheck("handlers", func() error { return nil })
+	defer removeB()
+
+	err := Ready()
+	if err == nil || err.Error() != "informer pod: cache not synced" {
+		t.Fatalf("unexpected readiness error %v", err)
+	}
+	if err := Live(); err != nil {
+		t.Fatalf("readiness checks must not affect liveness, got %v", err)
+	}
+
+	removeA()
+	if err := Ready(); err != nil {
+		t.Fatalf("expected the removed check not to run, got %v", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var Tests = []struct {
+		err    error
+		status int
+		body   string
+	}{
+		{nil, http.StatusOK, "ok"},
+		{fmt.Errorf("worker busy"), http.StatusServiceUnavailable, "worker pod: worker busy"},
+	}
+
+	for _, tt := range Tests {
+		err := tt.err
+		remove := AddLivenessCheck("worker pod", func() error { return err })
+		rec := httptest.NewRecorder()
+		LivenessHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+		remove()
+
+		if rec.Code != tt.status {
+			t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+		}
+		if got := strings.TrimSpace(rec.Body.String()); got != tt.body {
+			t.Errorf("expected body %q, got %q", tt.body, got)
+		}
+	}
+}
+
+func TestActivity(t *testing.T) {
+	a := &Activity{Timeout: time.Minute}
+	if err := a.Check(); err != nil {
+		t.Fatalf("an idle worker is not wedged, got %v", err)
+	}
+
+	a.Start()
+	if err := a.Check(); err != nil {
+		t.Fatalf("a worker that just started is not wedged, got %v", err)
+	}
+
+	a.since -= int64(2 * time.Minute)
+	if err := a.Check(); err == nil {
+		t.Fatalf("expected a worker busy for 2m to be wedged")
+	}
+
+	a.Done()
+	if err := a.Check(); err != nil {
+		t.Fatalf("a worker done with its item is not wedged, got %v", err)
+	}
+}
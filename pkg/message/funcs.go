@@ -0,0 +1,218 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package message
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// Funcs returns the helpers available to templates. They are named after, and
+// take their arguments in the same order as, their sprig counterparts, e.g.
+// {{ .Name | trunc 20 | upper }} or {{ .Labels.team | default "unknown" }}.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		// strings
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"trim":       strings.TrimSpace,
+		"trimAll":    func(cutset, s string) string { return strings.Trim(s, cutset) },
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old, new, s string) string { return strings.Replace(s, old, new, -1) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"repeat":     func(count int, s string) string { return strings.Repeat(s, count) },
+		"trunc":      trunc,
+		"quote":      func(s interface{}) string { return fmt.Sprintf("%q", fmt.Sprint(s)) },
+		"squote":     func(s interface{}) string { return "'" + fmt.Sprint(s) + "'" },
+		"indent":     indent,
+		"nindent":    func(spaces int, s string) string { return "\n" + indent(spaces, s) },
+		"splitList":  func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+
+		// defaults
+		"default":  func(def interface{}, given ...interface{}) interface{} { return coalesce(append(given, def)...) },
+		"empty":    empty,
+		"coalesce": coalesce,
+		"ternary": func(vt, vf interface{}, v bool) interface{} {
+			if v {
+				return vt
+			}
+			return vf
+		},
+
+		// lists and dictionaries
+		"list":      func(v ...interface{}) []interface{} { return v },
+		"keys":      keys,
+		"sortAlpha": sortAlpha,
+
+		// dates
+		"now":  time.Now,
+		"date": func(layout string, t time.Time) string { return t.Format(layout) },
+		"ago":  func(t time.Time) string { return time.Since(t).Round(time.Second).String() },
+
+		// encodings
+		"toJson":       toJSON,
+		"toPrettyJson": toPrettyJSON,
+		"fromJson":     fromJSON,
+		"b64enc":       func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	}
+}
+
+// trunc truncates a string to length characters, or removes the last -length characters
+func trunc(length int, s string) string {
+	r := []rune(s)
+	switch {
+	case length < 0 && -length < len(r):
+		return string(r[:len(r)+length])
+	case length >= 0 && length < len(r):
+		return string(r[:length])
+	}
+	return s
+}
+
+// title capitalises the first letter of each word of a string
+func title(s string) string {
+	start := true
+	return strings.Map(func(r rune) rune {
+		if start {
+			r = unicode.ToUpper(r)
+		}
+		start = unicode.IsSpace(r)
+		return r
+	}, s)
+}
+
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+// join joins the elements of a list, whatever their type
+func join(sep string, list interface{}) string {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return fmt.Sprint(list)
+	}
+	elems := make([]string, v.Len())
+	for i := range elems {
+		elems[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return strings.Join(elems, sep)
+}
+
+// empty reports whether a value is missing or the zero value of its type
+func empty(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return true
+	}
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return rv.IsZero()
+}
+
+// coalesce returns the first non-empty value
+func coalesce(v ...interface{}) interface{} {
+	for _, e := range v {
+		if !empty(e) {
+			return e
+		}
+	}
+	return nil
+}
+
+// keys returns the keys of a map, sorted
+func keys(m interface{}) []string {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map {
+		return nil
+	}
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, fmt.Sprint(k.Interface()))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortAlpha returns the elements of a list as strings, sorted
+func sortAlpha(list interface{}) []string {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []string{fmt.Sprint(list)}
+	}
+	elems := make([]string, v.Len())
+	for i := range elems {
+		elems[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	sort.Strings(elems)
+	return elems
+}
+
+func toJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func toPrettyJSON(v interface{}) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// fromJson decodes JSON, e.g. the objects of events; it accepts strings and raw JSON
+func fromJSON(v interface{}) (map[string]interface{}, error) {
+	var b []byte
+	switch s := v.(type) {
+	case string:
+		b = []byte(s)
+	case []byte:
+		b = s
+	case json.RawMessage:
+		b = s
+	default:
+		return nil, fmt.Errorf("fromJson: unsupported type %T", v)
+	}
+	m := map[string]interface{}{}
+	if len(b) == 0 {
+		return m, nil
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
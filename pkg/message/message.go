@@ -0,0 +1,96 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package message renders the text of notifications from user-defined Go
+text/template templates, executed with the event.Event being notified.
+Templates can use the helpers of Funcs, named after their sprig counterparts.
+*/
+package message
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+	"github.com/sirupsen/logrus"
+)
+
+// Parse parses a message template, with the helpers of Funcs.
+func Parse(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=zero").Funcs(Funcs()).Parse(text)
+}
+
+// Renderer renders the messages of events with the template of their kind,
+// or the default template. A nil Renderer renders the default messages.
+type Renderer struct {
+	message *template.Template
+	kinds   map[string]*template.Template
+}
+
+// NewRenderer parses the default template and the templates by kind.
+// Without any template, it returns nil.
+func NewRenderer(message string, kinds map[string]string) (*Renderer, error) {
+	if message == "" && len(kinds) == 0 {
+		return nil, nil
+	}
+
+	r := &Renderer{kinds: map[string]*template.Template{}}
+	var err error
+	if message != "" {
+		if r.message, err = Parse("message", message); err != nil {
+			return nil, err
+		}
+	}
+	for kind, text := range kinds {
+		if r.kinds[kind], err = Parse(kind, text); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Render renders the message of an event. Without a template for it, it
+// returns the default message of the event.
+func (r *Renderer) Render(e event.Event) (string, error) {
+	var t *template.Template
+	if r != nil {
+		t = r.kinds[e.Kind]
+		if t == nil {
+			t = r.message
+		}
+	}
+	if t == nil {
+		return e.Message(), nil
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, &e); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Message renders the message of an event, falling back to its default
+// message when the template fails.
+func (r *Renderer) Message(e event.Event) string {
+	msg, err := r.Render(e)
+	if err != nil {
+		logrus.Errorf("Error rendering the message of %s %s, sending the default message: %v", e.Kind, e.Name, err)
+		return e.Message()
+	}
+	return msg
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package message
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestRender(t *testing.T) {
+	r, err := NewRenderer("{{ .Reason }} {{ .Kind }} {{ .Namespace }}/{{ .Name }}", map[string]string{
+		"node": "Node {{ .Name }} {{ .Reason | lower }}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var Tests = []struct {
+		renderer *Renderer
+		e        event.Event
+		expected string
+	}{
+		{r, event.Event{Kind: "pod", Namespace: "default", Name: "foo", Reason: "Created"}, "Created pod default/foo"},
+		{r, event.Event{Kind: "node", Name: "node-1", Reason: "Deleted"}, "Node node-1 deleted"},
+		{nil, event.Event{Kind: "node", Name: "node-1", Reason: "Deleted"}, "A node `node-1` has been `Deleted`"},
+	}
+
+	for _, tt := range Tests {
+		msg, err := tt.renderer.Render(tt.e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, msg)
+		}
+	}
+}
+
+func TestRenderFallback(t *testing.T) {
+	r, err := NewRenderer("{{ .Nope }}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := event.Event{Kind: "pod", Namespace: "default", Name: "foo", Reason: "Created"}
+	if _, err := r.Render(e); err == nil {
+		t.Fatalf("expected an error for an unknown field")
+	}
+	if msg := r.Message(e); msg != e.Message() {
+		t.Errorf("expected the default message, got %q", msg)
+	}
+
+	if _, err := NewRenderer("{{ .Name", nil); err == nil {
+		t.Errorf("expected an error for an invalid template")
+	}
+}
+
+func TestFuncs(t *testing.T) {
+	e := event.Event{
+		Kind:      "deployment",
+		Namespace: "payments",
+		Name:      "checkout-api",
+		Labels:    map[string]string{"team": "payments", "app": "checkout"},
+		Diff:      []event.Change{{Path: "spec.replicas", Old: 1, New: 3}},
+		Timestamp: time.Date(2020, 11, 2, 10, 15, 0, 0, time.UTC),
+		Object:    json.RawMessage(`{"spec":{"replicas":3}}`),
+	}
+
+	var Tests = []struct {
+		template string
+		expected string
+	}{
+		{`{{ .Name | upper }}`, "CHECKOUT-API"},
+		{`{{ .Name | trunc 8 }}`, "checkout"},
+		{`{{ .Name | trimPrefix "checkout-" | title }}`, "Api"},
+		{`{{ "node  not ready" | title }}`, "Node  Not Ready"},
+		{`{{ .Name | replace "-" "_" | quote }}`, `"checkout_api"`},
+		{`{{ .Labels.owner | default "nobody" }}`, "nobody"},
+		{`{{ .Labels.team | default "nobody" }}`, "payments"},
+		{`{{ if empty .Annotations }}none{{ end }}`, "none"},
+		{`{{ keys .Labels | join "," }}`, "app,team"},
+		{`{{ .Timestamp | date "2006-01-02" }}`, "2020-11-02"},
+		{`{{ (fromJson .Object).spec.replicas }}`, "3"},
+		{`{{ .Labels | toJson }}`, `{"app":"checkout","team":"payments"}`},
+		{`{{ ternary "scaled" "changed" (eq (len .Diff) 1) }}`, "scaled"},
+		{`{{ .DiffMessage | indent 2 }}`, "  spec.replicas: 1 -> 3"},
+	}
+
+	for _, tt := range Tests {
+		r, err := NewRenderer(tt.template, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.template, err)
+		}
+		msg, err := r.Render(e)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.template, err)
+		}
+		if msg != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.template, tt.expected, msg)
+		}
+	}
+}
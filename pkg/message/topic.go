@@ -0,0 +1,83 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package message
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+// Topic renders the topics of events from a template, e.g. NATS subjects or
+// MQTT topics. The values of the event that the template inserts cannot
+// produce extra levels or wildcards: the separator of levels, the forbidden
+// characters and whitespace are replaced with _, and so are empty values.
+type Topic struct {
+	template *template.Template
+	replacer *strings.Replacer
+}
+
+// NewTopic parses the template of a topic whose levels are separated by
+// separator, and whose levels cannot contain the forbidden characters.
+// The template is executed with Cluster, Namespace, Kind, Name, Reason,
+// Status, Host, Component and Labels.
+func NewTopic(text, separator, forbidden string) (*Topic, error) {
+	t, err := Parse("topic", text)
+	if err != nil {
+		return nil, err
+	}
+	var oldnew []string
+	for _, c := range separator + forbidden + " \t\r\n" {
+		oldnew = append(oldnew, string(c), "_")
+	}
+	return &Topic{template: t, replacer: strings.NewReplacer(oldnew...)}, nil
+}
+
+// Render renders the topic of an event of a cluster.
+func (t *Topic) Render(e event.Event, cluster string) (string, error) {
+	labels := make(map[string]string, len(e.Labels))
+	for k, v := range e.Labels {
+		labels[k] = t.level(v)
+	}
+	data := map[string]interface{}{
+		"Cluster":   t.level(cluster),
+		"Namespace": t.level(e.Namespace),
+		"Kind":      t.level(e.Kind),
+		"Name":      t.level(e.Name),
+		"Reason":    t.level(e.Reason),
+		"Status":    t.level(e.Status),
+		"Host":      t.level(e.Host),
+		"Component": t.level(e.Component),
+		"Labels":    labels,
+	}
+
+	var buf bytes.Buffer
+	if err := t.template.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// level makes a value a valid level of the topic
+func (t *Topic) level(value string) string {
+	if value == "" {
+		return "_"
+	}
+	return t.replacer.Replace(value)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package message
+
+import (
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/event"
+)
+
+func TestTopic(t *testing.T) {
+	var Tests = []struct {
+		template, separator, forbidden string
+		e                              event.Event
+		cluster                        string
+		expected                       string
+	}{
+		{
+			"kubewatch.{{ .Cluster }}.{{ .Namespace }}.{{ .Kind }}.{{ .Reason }}", ".", "*>",
+			event.Event{Kind: "deployment", Namespace: "default", Name: "foo", Reason: "Updated"}, "prod",
+			"kubewatch.prod.default.deployment.Updated",
+		},
+		{
+			"kubewatch.{{ .Cluster }}.{{ .Namespace }}.{{ .Kind }}.{{ .Name }}", ".", "*>",
+			event.Event{Kind: "daemon set", Name: "kube-proxy.v1", Reason: "Updated"}, "",
+			"kubewatch._._.daemon_set.kube-proxy_v1",
+		},
+		{
+			"kubewatch/{{ .Labels.team | lower }}/{{ .Name }}", "/", "+#",
+			event.Event{Kind: "pod", Name: "foo#1", Labels: map[string]string{"team": "Pay/Ments"}}, "",
+			"kubewatch/pay_ments/foo_1",
+		},
+	}
+
+	for _, tt := range Tests {
+		topic, err := NewTopic(tt.template, tt.separator, tt.forbidden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := topic.Render(tt.e, tt.cluster)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, got)
+		}
+	}
+
+	if _, err := NewTopic("{{ .Kind", ".", ""); err == nil {
+		t.Errorf("expected an invalid template to fail")
+	}
+}
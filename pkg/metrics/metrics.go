@@ -0,0 +1,114 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package metrics defines the Prometheus metrics of kubewatch, including the
+ones of the client-go work queues, and serves them over HTTP.
+*/
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "kubewatch"
+
+// Reasons events are filtered out for
+const (
+	FilteredNamespace = "namespace"
+	FilteredUnchanged = "unchanged"
+	FilteredFilter    = "filter"
+)
+
+var (
+	// EventsObserved counts the events received from the informers.
+	EventsObserved = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_observed_total",
+		Help:      "Number of events observed, by resource type and event type.",
+	}, []string{"resource", "event_type"})
+
+	// EventsFiltered counts the events that are not notified.
+	EventsFiltered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_filtered_total",
+		Help:      "Number of events filtered out, by resource type, event type and reason (namespace, unchanged or filter).",
+	}, []string{"resource", "event_type", "reason"})
+
+	// EventsFailed counts the events whose processing failed.
+	EventsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_failed_total",
+		Help:      "Number of failed attempts to process an event, by resource type.",
+	}, []string{"resource"})
+
+	// NotificationsSent counts the notifications delivered by each handler.
+	NotificationsSent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_sent_total",
+		Help:      "Number of notifications delivered, by handler.",
+	}, []string{"handler"})
+
+	// NotificationsFailed counts the failed delivery attempts of each handler.
+	NotificationsFailed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_failed_total",
+		Help:      "Number of failed delivery attempts, by handler and error type (transient or permanent).",
+	}, []string{"handler", "error"})
+
+	// NotificationsDropped counts the notifications each handler gave up on.
+	NotificationsDropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_dropped_total",
+		Help:      "Number of notifications given up on after a permanent failure or too many retries, by handler.",
+	}, []string{"handler"})
+
+	// HandlerDuration observes how long each delivery attempt takes.
+	HandlerDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Duration of delivery attempts, by handler.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"handler"})
+
+	// InformerSynced tells whether the informer of each resource type has synced.
+	InformerSynced = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "informer_synced",
+		Help:      "Whether the informer cache of a resource type has synced (1) or not (0).",
+	}, []string{"resource"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		EventsObserved,
+		EventsFiltered,
+		EventsFailed,
+		NotificationsSent,
+		NotificationsFailed,
+		NotificationsDropped,
+		HandlerDuration,
+		InformerSynced,
+	)
+}
+
+// Handler returns the HTTP handler serving the metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package server serves the HTTP endpoints of kubewatch: Prometheus metrics on
+/metrics, and the liveness and readiness probes on /healthz and /readyz.
+*/
+package server
+
+import (
+	"net/http"
+
+	"github.com/bitnami-labs/kubewatch/pkg/health"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// NewHandler returns the HTTP handler of the kubewatch endpoints
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", health.LivenessHandler())
+	mux.Handle("/readyz", health.ReadinessHandler())
+	return mux
+}
+
+// Start serves the kubewatch endpoints on addr in the background
+func Start(addr string) {
+	server := &http.Server{Addr: addr, Handler: NewHandler()}
+	go func() {
+		logrus.Infof("Serving metrics and probes on %s", addr)
+		if err := server.ListenAndServe(); err != nil {
+			logrus.Fatal(err)
+		}
+	}()
+}
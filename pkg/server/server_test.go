@@ -0,0 +1,86 @@
+/*
+Copyright 2016 Skippbox, Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bitnami-labs/kubewatch/pkg/health"
+	"github.com/bitnami-labs/kubewatch/pkg/metrics"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestMetrics(t *testing.T) {
+	metrics.EventsObserved.WithLabelValues("pod", "create").Inc()
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "pod")
+	defer queue.ShutDown()
+	queue.Add("default/foo")
+
+	ts := httptest.NewServer(NewHandler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`kubewatch_events_observed_total{event_type="create",resource="pod"} 1`,
+		`kubewatch_workqueue_depth{name="pod"} 1`,
+	} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("expected %q in the metrics", want)
+		}
+	}
+}
+
+func TestProbes(t *testing.T) {
+	ts := httptest.NewServer(NewHandler())
+	defer ts.Close()
+
+	remove := health.AddReadinessCheck("informer pod", func() error { return fmt.Errorf("cache not synced") })
+	defer remove()
+
+	var Tests = []struct {
+		path   string
+		status int
+	}{
+		{"/healthz", http.StatusOK},
+		{"/readyz", http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range Tests {
+		resp, err := http.Get(ts.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, resp.StatusCode)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	apps_v1 "k8s.io/api/apps/v1"
@@ -10,7 +12,12 @@ import (
 	ext_v1beta1 "k8s.io/api/extensions/v1beta1"
 	rbac_v1beta1 "k8s.io/api/rbac/v1beta1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -30,6 +37,21 @@ func GetClient() kubernetes.Interface {
 	return clientset
 }
 
+// GetDynamicClient returns a k8s dynamic client to the request from inside of cluster
+func GetDynamicClient() dynamic.Interface {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		logrus.Fatalf("Can not get kubernetes config: %v", err)
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Can not create kubernetes dynamic client: %v", err)
+	}
+
+	return client
+}
+
 func buildOutOfClusterConfig() (*rest.Config, error) {
 	kubeconfigPath := os.Getenv("KUBECONFIG")
 	if kubeconfigPath == "" {
@@ -53,6 +75,40 @@ func GetClientOutOfCluster() kubernetes.Interface {
 	return clientset
 }
 
+// GetDynamicClientOutOfCluster returns a k8s dynamic client to the request from outside of cluster
+func GetDynamicClientOutOfCluster() dynamic.Interface {
+	config, err := buildOutOfClusterConfig()
+	if err != nil {
+		logrus.Fatalf("Can not get kubernetes config: %v", err)
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		logrus.Fatalf("Can not create kubernetes dynamic client: %v", err)
+	}
+
+	return client
+}
+
+// ParseGroupVersionResource parses a resource in "group/version/resource" form,
+// or "version/resource" for resources of the core group.
+func ParseGroupVersionResource(s string) (schema.GroupVersionResource, error) {
+	parts := strings.Split(s, "/")
+	for _, p := range parts {
+		if p == "" {
+			return schema.GroupVersionResource{}, fmt.Errorf("invalid resource %q: empty element", s)
+		}
+	}
+	switch len(parts) {
+	case 2:
+		return schema.GroupVersionResource{Version: parts[0], Resource: parts[1]}, nil
+	case 3:
+		return schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}, nil
+	default:
+		return schema.GroupVersionResource{}, fmt.Errorf("invalid resource %q: expected group/version/resource", s)
+	}
+}
+
 // GetObjectMetaData returns metadata of a given k8s object
 func GetObjectMetaData(obj interface{}) (objectMeta meta_v1.ObjectMeta) {
 
@@ -77,6 +133,8 @@ func GetObjectMetaData(obj interface{}) (objectMeta meta_v1.ObjectMeta) {
 		objectMeta = object.ObjectMeta
 	case *api_v1.Secret:
 		objectMeta = object.ObjectMeta
+	case *api_v1.ConfigMap:
+		objectMeta = object.ObjectMeta
 	case *ext_v1beta1.Ingress:
 		objectMeta = object.ObjectMeta
 	case *api_v1.Node:
@@ -87,6 +145,34 @@ func GetObjectMetaData(obj interface{}) (objectMeta meta_v1.ObjectMeta) {
 		objectMeta = object.ObjectMeta
 	case *api_v1.Event:
 		objectMeta = object.ObjectMeta
+	case *unstructured.Unstructured:
+		objectMeta = meta_v1.ObjectMeta{
+			Name:              object.GetName(),
+			Namespace:         object.GetNamespace(),
+			UID:               object.GetUID(),
+			ResourceVersion:   object.GetResourceVersion(),
+			Generation:        object.GetGeneration(),
+			CreationTimestamp: object.GetCreationTimestamp(),
+			DeletionTimestamp: object.GetDeletionTimestamp(),
+			Labels:            object.GetLabels(),
+			Annotations:       object.GetAnnotations(),
+			OwnerReferences:   object.GetOwnerReferences(),
+		}
 	}
 	return objectMeta
 }
+
+// GetObjectGroupVersionKind returns the API group, version and kind of a given k8s object,
+// which objects returned by typed clients do not carry themselves
+func GetObjectGroupVersionKind(obj interface{}) schema.GroupVersionKind {
+	switch object := obj.(type) {
+	case *unstructured.Unstructured:
+		return object.GroupVersionKind()
+	case runtime.Object:
+		gvks, _, err := scheme.Scheme.ObjectKinds(object)
+		if err == nil && len(gvks) > 0 {
+			return gvks[0]
+		}
+	}
+	return schema.GroupVersionKind{}
+}
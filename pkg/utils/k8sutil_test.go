@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	apps_v1 "k8s.io/api/apps/v1"
+	api_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseGroupVersionResource(t *testing.T) {
+	var Tests = []struct {
+		in    string
+		gvr   schema.GroupVersionResource
+		isErr bool
+	}{
+		{"apps/v1/statefulsets", schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "statefulsets"}, false},
+		{"cert-manager.io/v1/certificates", schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}, false},
+		{"v1/configmaps", schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, false},
+		{"statefulsets", schema.GroupVersionResource{}, true},
+		{"apps//statefulsets", schema.GroupVersionResource{}, true},
+		{"a/b/c/d", schema.GroupVersionResource{}, true},
+	}
+
+	for _, tt := range Tests {
+		gvr, err := ParseGroupVersionResource(tt.in)
+		if (err != nil) != tt.isErr {
+			t.Fatalf("ParseGroupVersionResource(%q): unexpected error %v", tt.in, err)
+		}
+		if gvr != tt.gvr {
+			t.Fatalf("ParseGroupVersionResource(%q): expected %v, got %v", tt.in, tt.gvr, gvr)
+		}
+	}
+}
+
+func TestGetObjectMetaDataUnstructured(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	obj.SetKind("Certificate")
+	obj.SetName("foo")
+	obj.SetNamespace("bar")
+	obj.SetLabels(map[string]string{"team": "payments"})
+
+	objectMeta := GetObjectMetaData(obj)
+	if objectMeta.Name != "foo" || objectMeta.Namespace != "bar" {
+		t.Fatalf("unexpected name/namespace: %s/%s", objectMeta.Namespace, objectMeta.Name)
+	}
+	if !reflect.DeepEqual(objectMeta.Labels, obj.GetLabels()) {
+		t.Fatalf("expected labels %v, got %v", obj.GetLabels(), objectMeta.Labels)
+	}
+}
+
+func TestGetObjectGroupVersionKind(t *testing.T) {
+	certificate := &unstructured.Unstructured{}
+	certificate.SetAPIVersion("cert-manager.io/v1")
+	certificate.SetKind("Certificate")
+
+	var Tests = []struct {
+		obj interface{}
+		gvk schema.GroupVersionKind
+	}{
+		{&api_v1.Pod{}, schema.GroupVersionKind{Version: "v1", Kind: "Pod"}},
+		{&apps_v1.Deployment{}, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}},
+		{certificate, schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}},
+		{nil, schema.GroupVersionKind{}},
+	}
+
+	for _, tt := range Tests {
+		if gvk := GetObjectGroupVersionKind(tt.obj); gvk != tt.gvk {
+			t.Errorf("GetObjectGroupVersionKind(%T): expected %v, got %v", tt.obj, tt.gvk, gvk)
+		}
+	}
+}
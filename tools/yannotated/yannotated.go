@@ -120,6 +120,8 @@ func emit(w io.Writer, types map[string]*ast.StructType, node *ast.StructType) e
 			}
 		case *ast.MapType:
 			fmt.Fprintf(w, " {}\n")
+		case *ast.ArrayType:
+			fmt.Fprintf(w, " []\n")
 		default:
 			return fmt.Errorf("unsupported field type: %T (%s)", field.Type, field.Type)
 		}
@@ -16,6 +16,8 @@ type Config struct {
 	// Rebar is another bar.
 	Rebar Bar `yaml:"rebar"`
 	Quz   map[string]string
+	// Qux is a list.
+	Qux []Bar `yaml:"qux"`
 }
 
 // Bar is a struct.
@@ -54,6 +56,8 @@ rebar:
   # Baz is baz.
   baz: 0
 quz: {}
+# Qux is a list.
+qux: []
 `
 	b, err := ioutil.ReadFile(tmp.Name())
 	if err != nil {